@@ -4,7 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/go-gywn/goutil"
@@ -31,18 +33,131 @@ type Database struct {
 
 // Webhook Webhook
 type Webhook struct {
-	CacheSyncSec     int                      `yaml:"cacheSyncSec"`
-	Template         string                   `yaml:"template"`
-	LabelMapper      map[string]string        `yaml:"labelMapper"`
-	AnnotationMapper map[string]string        `yaml:"annotationMapper"`
-	Targets          map[string]WebhookTarget `yaml:"targets"`
+	CacheSyncSec       int                      `yaml:"cacheSyncSec"`
+	Workers            int                      `yaml:"workers"`
+	QueueSize          int                      `yaml:"queueSize"`
+	EnqueueTimeoutMs   int                      `yaml:"enqueueTimeoutMs"`
+	RetryAfterSec      int                      `yaml:"retryAfterSec"`
+	ShutdownTimeoutSec int                      `yaml:"shutdownTimeoutSec"`
+	GroupMode          bool                     `yaml:"groupMode"`
+	Template           string                   `yaml:"template"`
+	TemplateDir        string                   `yaml:"templateDir"`
+	Templates          TemplateSelector         `yaml:"templates"`
+	TemplateWatch      TemplateWatch            `yaml:"templateWatch"`
+	LabelMapper        map[string]string        `yaml:"labelMapper"`
+	AnnotationMapper   map[string]string        `yaml:"annotationMapper"`
+	Targets            map[string]WebhookTarget `yaml:"targets"`
+	Levels             map[string][]string      `yaml:"levels"`
+	Routes             []Route                  `yaml:"routes"`
+	DefaultTargets     []string                 `yaml:"defaultTargets"`
+	Retry              Retry                    `yaml:"retry"`
+	DurableQueue       DurableQueue             `yaml:"durableQueue"`
+}
+
+// DurableQueue keep received alerts in hook_queue table until processed,
+// instances sharing the database claim rows with lease
+type DurableQueue struct {
+	Enabled  bool `yaml:"enabled"`
+	PollMs   int  `yaml:"pollMs"`
+	LeaseSec int  `yaml:"leaseSec"`
+}
+
+// TemplateSelector template name of alertname and level, target template comes first
+type TemplateSelector struct {
+	Alertnames map[string]string `yaml:"alertnames"`
+	Levels     map[string]string `yaml:"levels"`
+}
+
+// TemplateWatch reload template files changed on disk
+type TemplateWatch struct {
+	Enabled     bool `yaml:"enabled"`
+	IntervalSec int  `yaml:"intervalSec"`
+}
+
+// Retry resend failed delivery in outbox with exponential backoff
+type Retry struct {
+	MaxAttempts   int `yaml:"maxAttempts"`
+	BackoffSec    int `yaml:"backoffSec"`
+	MaxBackoffSec int `yaml:"maxBackoffSec"`
+	IntervalSec   int `yaml:"intervalSec"`
+	LeaseSec      int `yaml:"leaseSec"`
 }
 
 // WebhookTarget webhook target
 type WebhookTarget struct {
-	API    string
-	Params string
-	Method string
+	Type        string            `yaml:"type"`
+	Template    string            `yaml:"template"`
+	API         string            `yaml:"api"`
+	Params      string            `yaml:"params"`
+	Method      string            `yaml:"method"`
+	Body        string            `yaml:"body"`
+	ContentType string            `yaml:"contentType"`
+	Headers     map[string]string `yaml:"headers"`
+	Auth        TargetAuth        `yaml:"auth"`
+	Signature   TargetSignature   `yaml:"signature"`
+	Slack       SlackTarget       `yaml:"slack"`
+	Email       EmailTarget       `yaml:"email"`
+	PagerDuty   PagerDutyTarget   `yaml:"pagerduty"`
+	Telegram    TelegramTarget    `yaml:"telegram"`
+	Teams       TeamsTarget       `yaml:"teams"`
+}
+
+// TargetAuth authorization header, type is "basic" (user, pass) or "bearer" (token)
+// pass and token are encrypted like database pass if encrypted is true
+type TargetAuth struct {
+	Type      string `yaml:"type"`
+	User      string `yaml:"user"`
+	Pass      string `yaml:"pass"`
+	Token     string `yaml:"token"`
+	Encrypted bool   `yaml:"encrypted"`
+}
+
+// TargetSignature HMAC-SHA256 signature of "timestamp.body" with secret
+type TargetSignature struct {
+	Secret          string `yaml:"secret"`
+	Encrypted       bool   `yaml:"encrypted"`
+	Header          string `yaml:"header"`
+	TimestampHeader string `yaml:"timestampHeader"`
+}
+
+// EmailTarget smtp mail option, pass is encrypted like database pass if encrypted is true
+type EmailTarget struct {
+	Host               string   `yaml:"host"`
+	TLS                string   `yaml:"tls"`
+	InsecureSkipVerify bool     `yaml:"insecureSkipVerify"`
+	User               string   `yaml:"user"`
+	Pass               string   `yaml:"pass"`
+	Encrypted          bool     `yaml:"encrypted"`
+	From               string   `yaml:"from"`
+	To                 []string `yaml:"to"`
+	Cc                 []string `yaml:"cc"`
+	Subject            string   `yaml:"subject"`
+}
+
+// PagerDutyTarget pagerduty events api v2 option, severity maps level to pagerduty severity
+type PagerDutyTarget struct {
+	RoutingKey string            `yaml:"routingKey"`
+	Severity   map[string]string `yaml:"severity"`
+}
+
+// TelegramTarget telegram bot option, parseMode is "", "Markdown", "MarkdownV2" or "HTML"
+type TelegramTarget struct {
+	Token     string   `yaml:"token"`
+	ChatIDs   []string `yaml:"chatIDs"`
+	ParseMode string   `yaml:"parseMode"`
+}
+
+// TeamsTarget microsoft teams option, messageCard sends legacy MessageCard instead of Adaptive Card
+type TeamsTarget struct {
+	MessageCard bool `yaml:"messageCard"`
+}
+
+// SlackTarget slack incoming webhook option
+type SlackTarget struct {
+	Channel   string            `yaml:"channel"`
+	Username  string            `yaml:"username"`
+	IconEmoji string            `yaml:"iconEmoji"`
+	Colors    map[string]string `yaml:"colors"`
 }
 
 // CONF default config (overwritten by configure.yml)
@@ -61,7 +176,16 @@ func init() {
 	var config, password string
 	flag.StringVar(&config, "config", "configure.yml", "configuration")
 	flag.StringVar(&password, "password", "", "password")
-	flag.Parse()
+
+	// go test flags are parsed by testing package
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if !strings.HasPrefix(arg, "-test.") {
+			args = append(args, arg)
+		}
+	}
+	isTest := len(args) < len(os.Args)-1
+	flag.CommandLine.Parse(args)
 
 	// ==========================
 	// Load default configuration
@@ -75,7 +199,10 @@ func init() {
 	// ==========================
 	var b []byte
 	if b, err = ioutil.ReadFile(config); err != nil {
-		logger.Fatal(err)
+		// go test runs in package directory, default configuration is used only if there is no file
+		if !isTest || !os.IsNotExist(err) {
+			logger.Fatal(err)
+		}
 	}
 
 	if err = yaml.Unmarshal(b, &CONF); err != nil {
@@ -106,7 +233,84 @@ func init() {
 		logger.Fatal("Mapper has no entry, exit")
 	}
 
-	logger.Println("start with", CONF)
+	// Level targets check
+	for level, names := range CONF.Webhook.Levels {
+		for _, name := range names {
+			if _, ok := CONF.Webhook.Targets[name]; !ok {
+				logger.Fatal("Level '", level, "' target '", name, "' not in targets")
+			}
+		}
+	}
+
+	// Default targets check
+	for _, name := range CONF.Webhook.DefaultTargets {
+		if _, ok := CONF.Webhook.Targets[name]; !ok {
+			logger.Fatal("Default target '", name, "' not in targets")
+		}
+	}
+
+	// Route tree check
+	if err = CONF.Webhook.CompileRoutes(); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Println("start with", CONF.masked())
+}
+
+// masked copy to log, secrets and url paths of targets are hidden
+func (o Config) masked() Config {
+	o.Key = maskSecret(o.Key)
+	o.Database.Pass = maskSecret(o.Database.Pass)
+
+	targets := map[string]WebhookTarget{}
+	for name, target := range o.Webhook.Targets {
+		target.API = maskURL(target.API)
+		headers := map[string]string{}
+		for k, v := range target.Headers {
+			headers[k] = maskSecret(v)
+		}
+		target.Headers = headers
+		target.Auth.Pass = maskSecret(target.Auth.Pass)
+		target.Auth.Token = maskSecret(target.Auth.Token)
+		target.Signature.Secret = maskSecret(target.Signature.Secret)
+		target.Email.Pass = maskSecret(target.Email.Pass)
+		target.PagerDuty.RoutingKey = maskSecret(target.PagerDuty.RoutingKey)
+		target.Telegram.Token = maskSecret(target.Telegram.Token)
+		targets[name] = target
+	}
+	o.Webhook.Targets = targets
+	return o
+}
+
+// maskSecret hide non-empty secret
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "******"
+}
+
+// maskURL keep scheme and host only, webhook path and query can have token
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return maskSecret(s)
+	}
+	if u.Path == "" && u.RawQuery == "" && u.User == nil {
+		return s
+	}
+	return u.Scheme + "://" + u.Host + "/******"
+}
+
+// GetTargetNames target names of level from levels, or the target named as level
+func (o *Webhook) GetTargetNames(level string) []string {
+	if names, ok := o.Levels[level]; ok {
+		return names
+	}
+	if _, ok := o.Targets[level]; ok {
+		return []string{level}
+	}
+	return nil
 }
 
 // GetLocation GetLocation
@@ -128,7 +332,17 @@ database:
 
 webhook:
   cacheSyncSec: 60
+  workers: 5
+  queueSize: 100
+  enqueueTimeoutMs: 1000
+  retryAfterSec: 30
+  shutdownTimeoutSec: 10
+  groupMode: false
   template: "tempalte.tpl"
+  templateDir: "templates"
+  templateWatch:
+    enabled: false
+    intervalSec: 5
   labelMapper:
     alertname: "alertname"
     instance: "instance"
@@ -139,11 +353,23 @@ webhook:
     summary: "summary"
   targets:
     critical:
+      type: "http"
       api: "http://127.0.0.1:52802/webhook/hook/test"
       params: "id=12345&message=[[message]]"
       method: "POST"
     warning:
+      type: "http"
       api: "http://127.0.0.1:52802/webhook/hook/test"
       params: "id=54321&message=[[message]]"
       method: "POST"
+  retry:
+    maxAttempts: 5
+    backoffSec: 10
+    maxBackoffSec: 600
+    intervalSec: 5
+    leaseSec: 300
+  durableQueue:
+    enabled: false
+    pollMs: 500
+    leaseSec: 300
 `
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConfigMasked(t *testing.T) {
+	conf := Config{Key: "aes-key", Database: Database{User: "dbadmin", Pass: "db-pass"}}
+	conf.Webhook.Targets = map[string]WebhookTarget{
+		"slack": {Type: "slack", API: "https://hooks.slack.com/services/T000/B000/slack-path"},
+		"http": {
+			API:       "http://127.0.0.1:8080?token=query-token",
+			Headers:   map[string]string{"X-Api-Key": "header-key"},
+			Auth:      TargetAuth{Type: "bearer", Token: "bearer-token"},
+			Signature: TargetSignature{Secret: "hmac-secret"},
+		},
+		"basic":     {API: "http://127.0.0.1:8080", Auth: TargetAuth{Type: "basic", User: "webhook", Pass: "basic-pass"}},
+		"email":     {Email: EmailTarget{Host: "smtp.example.com:587", Pass: "smtp-pass"}},
+		"pagerduty": {PagerDuty: PagerDutyTarget{RoutingKey: "routing-key"}},
+		"telegram":  {Telegram: TelegramTarget{Token: "123:bot-token", ChatIDs: []string{"100"}}},
+	}
+
+	masked := fmt.Sprint(conf.masked())
+	for _, secret := range []string{"aes-key", "db-pass", "slack-path", "query-token", "header-key",
+		"bearer-token", "hmac-secret", "basic-pass", "smtp-pass", "routing-key", "bot-token"} {
+		if strings.Contains(masked, secret) {
+			t.Errorf("masked config has %s", secret)
+		}
+	}
+	for _, value := range []string{"dbadmin", "https://hooks.slack.com/******", "http://127.0.0.1:8080 ", "smtp.example.com:587", "webhook"} {
+		if !strings.Contains(masked, value) {
+			t.Errorf("masked config has no %s", value)
+		}
+	}
+
+	// original config is not changed
+	if conf.Webhook.Targets["telegram"].Telegram.Token != "123:bot-token" || conf.Webhook.Targets["http"].Headers["X-Api-Key"] != "header-key" {
+		t.Error("original config is masked")
+	}
+}
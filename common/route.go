@@ -0,0 +1,147 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Route alert route like alertmanager route tree
+// matchers are `label=value`, `label!=value`, `label=~regex` or `label!~regex`
+type Route struct {
+	Name     string   `yaml:"name"`
+	Matchers []string `yaml:"matchers"`
+	Targets  []string `yaml:"targets"`
+	Continue bool     `yaml:"continue"`
+	Routes   []Route  `yaml:"routes"`
+
+	matchers []matcher
+}
+
+// RouteMatch matched route and its targets
+type RouteMatch struct {
+	Route   string   `json:"route"`
+	Targets []string `json:"targets"`
+}
+
+type matcher struct {
+	name  string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+var matcherRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*(.*?)\s*$`)
+
+// CompileRoutes parse matchers of route tree and check targets
+func (o *Webhook) CompileRoutes() error {
+	for i := range o.Routes {
+		if err := o.Routes[i].compile(o.Targets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchRoutes match labels with routes, empty if no route matched
+func (o *Webhook) MatchRoutes(labels map[string]string) []RouteMatch {
+	return matchRoutes(o.Routes, labels, "", nil)
+}
+
+// matchRoutes the deepest matched routes win, a route without targets uses parent targets
+func matchRoutes(routes []Route, labels map[string]string, parent string, parentTargets []string) (matches []RouteMatch) {
+	for i := range routes {
+		route := &routes[i]
+		if !route.match(labels) {
+			continue
+		}
+
+		path := route.path(parent, i)
+		targets := route.Targets
+		if len(targets) == 0 {
+			targets = parentTargets
+		}
+
+		if childMatches := matchRoutes(route.Routes, labels, path, targets); len(childMatches) > 0 {
+			matches = append(matches, childMatches...)
+		} else {
+			matches = append(matches, RouteMatch{Route: path, Targets: targets})
+		}
+
+		if !route.Continue {
+			break
+		}
+	}
+	return
+}
+
+// compile parse matchers of route tree and check targets
+func (o *Route) compile(targets map[string]WebhookTarget) error {
+	o.matchers = nil
+	for _, s := range o.Matchers {
+		m := matcherRegexp.FindStringSubmatch(s)
+		if m == nil {
+			return fmt.Errorf("route '%s' invalid matcher - %s", o.Name, s)
+		}
+
+		mt := matcher{name: m[1], op: m[2], value: strings.Trim(m[3], `"`)}
+		if mt.op == "=~" || mt.op == "!~" {
+			re, err := regexp.Compile("^(?:" + mt.value + ")$")
+			if err != nil {
+				return fmt.Errorf("route '%s' invalid regex - %s", o.Name, err)
+			}
+			mt.re = re
+		}
+		o.matchers = append(o.matchers, mt)
+	}
+
+	for _, name := range o.Targets {
+		if _, ok := targets[name]; !ok {
+			return fmt.Errorf("route '%s' target '%s' not in targets", o.Name, name)
+		}
+	}
+
+	for i := range o.Routes {
+		if err := o.Routes[i].compile(targets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Route) match(labels map[string]string) bool {
+	for _, m := range o.matchers {
+		value := labels[m.name]
+		switch m.op {
+		case "=":
+			if value != m.value {
+				return false
+			}
+		case "!=":
+			if value == m.value {
+				return false
+			}
+		case "=~":
+			if !m.re.MatchString(value) {
+				return false
+			}
+		case "!~":
+			if m.re.MatchString(value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// path route name, or index if no name
+func (o *Route) path(parent string, index int) string {
+	name := o.Name
+	if name == "" {
+		name = fmt.Sprintf("%d", index)
+	}
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
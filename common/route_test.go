@@ -0,0 +1,91 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchRoutes(t *testing.T) {
+	targets := map[string]WebhookTarget{"dba": {}, "sre": {}, "oncall": {}}
+	routes := []Route{
+		{
+			Name:     "db",
+			Matchers: []string{`job=~"mysql|redis"`},
+			Targets:  []string{"dba"},
+			Routes: []Route{
+				{Name: "critical", Matchers: []string{"level=critical"}, Targets: []string{"oncall"}, Continue: true},
+				{Name: "not-dev", Matchers: []string{`env!="dev"`}},
+			},
+		},
+		{Matchers: []string{"job!~node.*"}, Targets: []string{"sre"}, Continue: true},
+		{Name: "node", Matchers: []string{"job=node"}, Targets: []string{"sre"}},
+		{Name: "empty", Matchers: []string{"job=batch"}},
+	}
+	for i := range routes {
+		if err := routes[i].compile(targets); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []RouteMatch
+	}{
+		{
+			name:   "child with continue and child with parent targets",
+			labels: map[string]string{"job": "mysql", "level": "critical", "env": "prod"},
+			want:   []RouteMatch{{Route: "db/critical", Targets: []string{"oncall"}}, {Route: "db/not-dev", Targets: []string{"dba"}}},
+		},
+		{
+			name:   "parent when no child matched",
+			labels: map[string]string{"job": "redis", "level": "warning", "env": "dev"},
+			want:   []RouteMatch{{Route: "db", Targets: []string{"dba"}}},
+		},
+		{
+			name:   "unnamed route continues",
+			labels: map[string]string{"job": "batch"},
+			want:   []RouteMatch{{Route: "1", Targets: []string{"sre"}}, {Route: "empty"}},
+		},
+		{
+			name:   "regex is anchored",
+			labels: map[string]string{"job": "node"},
+			want:   []RouteMatch{{Route: "node", Targets: []string{"sre"}}},
+		},
+		{
+			name:   "no match",
+			labels: map[string]string{"job": "node_exporter"},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchRoutes(routes, tt.labels, "", nil)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchRoutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteCompile(t *testing.T) {
+	targets := map[string]WebhookTarget{"dba": {}}
+	tests := []struct {
+		name    string
+		route   Route
+		wantErr bool
+	}{
+		{name: "valid", route: Route{Matchers: []string{"job=mysql", `level=~"crit.*"`}, Targets: []string{"dba"}}},
+		{name: "invalid matcher", route: Route{Matchers: []string{"job"}}, wantErr: true},
+		{name: "invalid regex", route: Route{Matchers: []string{"job=~("}}, wantErr: true},
+		{name: "unknown target", route: Route{Targets: []string{"sre"}}, wantErr: true},
+		{name: "unknown child target", route: Route{Routes: []Route{{Targets: []string{"sre"}}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.route.compile(targets); (err != nil) != tt.wantErr {
+				t.Errorf("compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,24 +1,26 @@
 package handler
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"text/template"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-gywn/goutil"
 	"github.com/go-gywn/webhook-go/common"
-	t "github.com/prometheus/alertmanager/template"
 )
 
 var routerGroup *gin.RouterGroup
+var server = &http.Server{}
 var logger = goutil.GetLogger()
 var crypt = goutil.GetCrypto(common.CONF.Key)
 var fileUtil = goutil.GetFileUtil()
 
-var chanHook = make(chan t.Alert, 100)
-var hookTemplate *template.Template
 var hookDefaultTemplate *template.Template
 var defaultTemplate = `[{{ .status }}] {{ .summary }}
 > Instance: {{ .instance }}
@@ -28,6 +30,12 @@ var defaultTemplate = `[{{ .status }}] {{ .summary }}
 > End: {{ .endsAt.Format "01/02 15:04:05 MST" }}{{ end }}
 > Description: {{ .description }}`
 
+// instanceID owner of claimed rows in database shared by instances
+var instanceID = func() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().Unix())
+}()
+
 var labelAlertname = common.CONF.Webhook.LabelMapper["alertname"]
 var labelInstance = common.CONF.Webhook.LabelMapper["instance"]
 var labelLevel = common.CONF.Webhook.LabelMapper["level"]
@@ -64,5 +72,25 @@ func StartHandler() error {
 	startHook(router.Group(common.CONF.Base))
 
 	// startHookThread(make(chan t.Alert, 100))
-	return router.Run(common.CONF.Port)
+	server.Addr = common.CONF.Port
+	server.Handler = router
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// StopHandler stop API server, then drain alert queue until shutdown timeout
+func StopHandler() error {
+	timeout := time.Duration(common.CONF.Webhook.ShutdownTimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("server shutdown - ", err)
+	}
+	return stopHookQueues(ctx)
 }
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []string
+	}{
+		{name: "same", a: "a\nb", b: "a\nb", want: []string{"  a", "  b"}},
+		{name: "changed line", a: "a\nb\nc", b: "a\nx\nc", want: []string{"  a", "- b", "+ x", "  c"}},
+		{name: "added lines", a: "a", b: "a\nb\nc", want: []string{"  a", "+ b", "+ c"}},
+		{name: "removed lines", a: "a\nb\nc", b: "c", want: []string{"- a", "- b", "  c"}},
+		{name: "moved line", a: "a\nb\nc", b: "b\nc\na", want: []string{"- a", "  b", "  c", "+ a"}},
+		{name: "from empty", a: "", b: "a", want: []string{"- ", "+ a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
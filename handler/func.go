@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-gywn/webhook-go/common"
 )
 
 // ErrorIf return boolean if error
@@ -19,6 +20,24 @@ func ErrorIf(c *gin.Context, err error) bool {
 	return false
 }
 
+// UnavailableIf return boolean if error, respond 503 with Retry-After
+func UnavailableIf(c *gin.Context, err error) bool {
+	if err != nil {
+		retryAfter := common.CONF.Webhook.RetryAfterSec
+		if retryAfter <= 0 {
+			retryAfter = 30
+		}
+		c.Header("Retry-After", common.IntString(retryAfter))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "fail",
+			"result": err.Error(),
+		})
+		c.Abort()
+		return true
+	}
+	return false
+}
+
 // Success normal message if success
 func Success(c *gin.Context, result interface{}) {
 	c.JSON(http.StatusOK, gin.H{
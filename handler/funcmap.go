@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs functions for message and target templates, arguments are ordered for pipeline
+// e.g. {{ .description | truncate 100 }}, {{ .level | default "info" | toUpper }}
+var templateFuncs = template.FuncMap{
+	"toUpper":    strings.ToUpper,
+	"toLower":    strings.ToLower,
+	"title":      strings.Title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+	"join":       func(sep string, list []string) string { return strings.Join(list, sep) },
+	"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+	"truncate":   func(max int, s string) string { return truncate(s, max) },
+	"regexMatch": func(pattern, s string) (bool, error) {
+		return regexp.MatchString(pattern, s)
+	},
+	"regexReplace": func(pattern, repl, s string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllString(s, repl), nil
+	},
+	"default": func(def interface{}, v interface{}) interface{} {
+		if isEmptyValue(v) {
+			return def
+		}
+		return v
+	},
+	"json":           toJSON,
+	"toJSON":         toJSON,
+	"jsonEscape":     jsonEscape,
+	"telegramEscape": telegramEscape,
+	"now":            time.Now,
+	"since":          func(t time.Time) time.Duration { return time.Since(t).Truncate(time.Second) },
+	"inZone": func(zone string, t time.Time) (time.Time, error) {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return t, err
+		}
+		return t.In(loc), nil
+	},
+	"humanizeDuration": humanizeDuration,
+}
+
+// parseTemplate parse template with templateFuncs
+func parseTemplate(name string, content string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(content)
+}
+
+// toJSON JSON value of v
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// jsonEscape JSON string without quotes
+func jsonEscape(s string) (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b[1 : len(b)-1]), nil
+}
+
+// humanizeDuration duration like 2d3h4m5s, number is seconds like prometheus
+func humanizeDuration(v interface{}) (string, error) {
+	var d time.Duration
+	switch n := v.(type) {
+	case time.Duration:
+		d = n
+	case int:
+		d = time.Duration(n) * time.Second
+	case int64:
+		d = time.Duration(n) * time.Second
+	case float64:
+		d = time.Duration(n * float64(time.Second))
+	default:
+		return "", fmt.Errorf("humanizeDuration: unsupported type %T", v)
+	}
+
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d < time.Second {
+		return sign + d.String(), nil
+	}
+
+	d = d.Truncate(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	var b strings.Builder
+	b.WriteString(sign)
+	if days > 0 {
+		fmt.Fprintf(&b, "%dd", days)
+	}
+	for _, unit := range []struct {
+		d time.Duration
+		s string
+	}{{time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"}} {
+		if n := d / unit.d; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.s)
+			d -= n * unit.d
+		}
+	}
+	return b.String(), nil
+}
+
+// isEmptyValue nil, zero or empty value
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
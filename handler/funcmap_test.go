@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{v: 0, want: "0s"},
+		{v: 45, want: "45s"},
+		{v: int64(3600), want: "1h"},
+		{v: 90061, want: "1d1h1m1s"},
+		{v: 2.5, want: "2s"},
+		{v: 0.25, want: "250ms"},
+		{v: -125, want: "-2m5s"},
+		{v: 3*time.Hour + 4*time.Minute + 500*time.Millisecond, want: "3h4m"},
+		{v: 48 * time.Hour, want: "2d"},
+		{v: "10s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := humanizeDuration(tt.v)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("humanizeDuration(%v) error = %v, wantErr %v", tt.v, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("humanizeDuration(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	vars := map[string]interface{}{
+		"level":    "",
+		"instance": `db1:3306 "primary"`,
+		"labels":   map[string]string{"job": "mysql"},
+		"desc":     "mysql is down for a while",
+	}
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`{{ .level | default "info" | toUpper }}`, "INFO"},
+		{`{{ .instance | regexReplace ":[0-9]+.*$" "" }}`, "db1"},
+		{`{{ .desc | truncate 10 }}`, "mysql i..."},
+		{`{{ .desc | truncate 2 }}`, "my"},
+		{`{{ json .labels }}`, `{"job":"mysql"}`},
+		{`{{ toJSON .labels }}`, `{"job":"mysql"}`},
+		{`"{{ jsonEscape .instance }}"`, `"db1:3306 \"primary\""`},
+	}
+	for _, tt := range tests {
+		tpl, err := parseTemplate("test", tt.content)
+		if err != nil {
+			t.Errorf("%s - %s", tt.content, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err = tpl.Execute(&buf, vars); err != nil {
+			t.Errorf("%s - %s", tt.content, err)
+			continue
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+
+	t "github.com/prometheus/alertmanager/template"
+)
+
+// groupData alertmanager notification with its group key
+type groupData struct {
+	t.Data
+	GroupKey string `json:"groupKey"`
+}
+
+// sendGroup send one message for whole alertmanager notification in group mode
+func sendGroup(data groupData) {
+	alert := groupAlert(data.Data)
+	jsonMarshal, _ := json.Marshal(data)
+	processHook(getGroupHookID(data), alert, groupVars(data.Data, alert), string(jsonMarshal), true)
+}
+
+// groupAlert alert of notification, common labels and annotations,
+// the first start and the last end of alerts
+func groupAlert(data t.Data) t.Alert {
+	alert := t.Alert{
+		Status:       data.Status,
+		Labels:       t.KV{},
+		Annotations:  t.KV{},
+		GeneratorURL: data.ExternalURL,
+	}
+	for k, v := range data.CommonLabels {
+		alert.Labels[k] = v
+	}
+	for k, v := range data.CommonAnnotations {
+		alert.Annotations[k] = v
+	}
+	for i, a := range data.Alerts {
+		if i == 0 || a.StartsAt.Before(alert.StartsAt) {
+			alert.StartsAt = a.StartsAt
+		}
+		if a.EndsAt.After(alert.EndsAt) {
+			alert.EndsAt = a.EndsAt
+		}
+	}
+	return alert
+}
+
+// groupVars alert variables with notification data, same names as alertmanager template
+func groupVars(data t.Data, alert t.Alert) map[string]interface{} {
+	vars := alertVars(alert)
+	vars["Alerts"] = data.Alerts
+	vars["GroupLabels"] = data.GroupLabels
+	vars["CommonLabels"] = data.CommonLabels
+	vars["CommonAnnotations"] = data.CommonAnnotations
+	vars["ExternalURL"] = data.ExternalURL
+	vars["Receiver"] = data.Receiver
+	return vars
+}
+
+// getGroupHookID hook id from alertmanager group key, receiver and group labels if no key,
+// every notification of a group has the same id as alerts join and leave
+func getGroupHookID(data groupData) string {
+	if data.GroupKey != "" {
+		return crypt.MD5(data.GroupKey)
+	}
+	k := data.Receiver
+	for _, pair := range data.GroupLabels.SortedPairs() {
+		k += pair.Name + "=" + pair.Value + ","
+	}
+	return crypt.MD5(k)
+}
@@ -1,14 +1,10 @@
 package handler
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"strings"
-	"text/template"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,24 +15,63 @@ import (
 
 // StartHostAPI start host API
 func startHook(r *gin.RouterGroup) {
+	// =======================
+	// check target notifier
+	// =======================
+	for name, target := range common.CONF.Webhook.Targets {
+		if _, err := GetNotifier(target.Type); err != nil {
+			logger.Fatal("target '", name, "' - ", err)
+		}
+		contents := []string{target.Body}
+		for _, v := range target.Headers {
+			contents = append(contents, v)
+		}
+		for _, content := range contents {
+			if _, err := getTargetTemplate(content); err != nil {
+				logger.Fatal("target '", name, "' template - ", err)
+			}
+		}
+	}
+
 	// =======================
 	// start message thread
 	// =======================
-	for i := 0; i < 5; i++ {
-		hookSender(chanHook)
+	for _, queue := range newHookQueues() {
+		hookSender(queue)
 	}
+	startRetrier()
 
 	// =======================
 	// load template
 	// =======================
-	if err := loadTemplate(common.CONF.Webhook.Template); err != nil {
-		fmt.Println(err)
-		loadTemplate()
+	loadTemplates()
+	for _, names := range []map[string]string{common.CONF.Webhook.Templates.Alertnames, common.CONF.Webhook.Templates.Levels} {
+		for k, name := range names {
+			if !hasTemplate(name) {
+				logger.Fatal("template '", name, "' of '", k, "' not found")
+			}
+		}
+	}
+	for k, target := range common.CONF.Webhook.Targets {
+		if target.Template != "" && !hasTemplate(target.Template) {
+			logger.Fatal("target '", k, "' template '", target.Template, "' not found")
+		}
+	}
+	if common.CONF.Webhook.TemplateWatch.Enabled {
+		startTemplateWatcher()
+	}
+
+	// =======================
+	// restore alerts saved at last shutdown
+	// =======================
+	restoreHookQueues()
+	if common.CONF.Webhook.DurableQueue.Enabled {
+		startQueuePoller()
 	}
 
 	r.POST("/hook/send", func(c *gin.Context) {
 		var err error
-		var params t.Data
+		var params groupData
 
 		// bind template json data
 		err = c.BindJSON(&params)
@@ -45,8 +80,15 @@ func startHook(r *gin.RouterGroup) {
 			return
 		}
 
-		for _, alert := range params.Alerts {
-			chanHook <- alert
+		// group mode sends one message for the notification
+		if common.CONF.Webhook.GroupMode {
+			err = enqueueGroup(params)
+		} else {
+			err = enqueueAll(params.Alerts)
+		}
+		if UnavailableIf(c, err) {
+			logger.Error(err)
+			return
 		}
 
 		Success(c, "ok")
@@ -68,7 +110,11 @@ func startHook(r *gin.RouterGroup) {
 			return
 		}
 
-		chanHook <- toPromAlert(params)
+		err = enqueue(toPromAlert(params))
+		if UnavailableIf(c, err) {
+			logger.Error(err)
+			return
+		}
 		Success(c, "ok")
 	})
 
@@ -88,7 +134,11 @@ func startHook(r *gin.RouterGroup) {
 			return
 		}
 
-		chanHook <- toPromAlert(params)
+		err = enqueue(toPromAlert(params))
+		if UnavailableIf(c, err) {
+			logger.Error(err)
+			return
+		}
 		Success(c, "ok")
 	})
 
@@ -127,7 +177,22 @@ func startHook(r *gin.RouterGroup) {
 	})
 
 	r.POST("/hook/template/reload", func(c *gin.Context) {
-		err := loadTemplate(common.CONF.Webhook.Template)
+		// reload all templates if no name
+		name := c.Query("name")
+		if name == "" {
+			name, _ = c.GetPostForm("name")
+		}
+		if name == "" {
+			err := reloadTemplates(templateAuthor(c))
+			if ErrorIf(c, err) {
+				logger.Error(err)
+				return
+			}
+			Success(c, getTemplateNames())
+			return
+		}
+
+		err := reloadTemplate(name, templateAuthor(c))
 		if ErrorIf(c, err) {
 			logger.Error(err)
 			return
@@ -135,15 +200,102 @@ func startHook(r *gin.RouterGroup) {
 		Success(c, "ok")
 	})
 
+	r.GET("/hook/templates", func(c *gin.Context) {
+		Success(c, getTemplateNames())
+	})
+
 	r.GET("/hook/template", func(c *gin.Context) {
-		content := readTemplate(common.CONF.Webhook.Template)
+		content, err := readTemplate(c.Query("name"))
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
 		Success(c, content)
 	})
 
 	r.POST("/hook/template", func(c *gin.Context) {
 		var err error
+		name, _ := c.GetPostForm("name")
+		content, _ := c.GetPostForm("content")
+		comment, _ := c.GetPostForm("comment")
+		err = writeTemplate(name, content, templateAuthor(c), comment)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, "OK")
+	})
+
+	r.POST("/hook/template/render", func(c *gin.Context) {
 		content, _ := c.GetPostForm("content")
-		err = writeTemplate(common.CONF.Webhook.Template, content)
+		hookID, _ := c.GetPostForm("hook_id")
+		payload, _ := c.GetPostForm("payload")
+		messages, err := renderTemplate(content, hookID, payload)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, messages)
+	})
+
+	r.GET("/hook/template/failures", func(c *gin.Context) {
+		var err error
+		var params model.HookDetail
+		var lists []model.HookDetail
+
+		rowsValue, _ := c.GetQuery("rows")
+		limit := common.ParseInt(rowsValue)
+		if limit == 0 {
+			limit = 100
+		}
+
+		lists, err = params.GetTemplateFailures(limit)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, lists)
+	})
+
+	r.GET("/hook/template/versions", func(c *gin.Context) {
+		var err error
+		var lists []model.HookTemplate
+
+		name := c.Query("name")
+		if name == "" {
+			name = defaultTemplateName
+		}
+
+		rowsValue, _ := c.GetQuery("rows")
+		limit := common.ParseInt(rowsValue)
+		if limit == 0 {
+			limit = 100
+		}
+
+		lists, err = (&model.HookTemplate{Name: name}).GetList(limit)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, lists)
+	})
+
+	r.GET("/hook/template/diff", func(c *gin.Context) {
+		from := common.ParseInt(c.Query("from"))
+		to := common.ParseInt(c.Query("to"))
+		lines, err := diffTemplate(c.Query("name"), from, to)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, lines)
+	})
+
+	r.POST("/hook/template/rollback", func(c *gin.Context) {
+		name, _ := c.GetPostForm("name")
+		version, _ := c.GetPostForm("version")
+		comment, _ := c.GetPostForm("comment")
+		err := rollbackTemplate(name, common.ParseInt(version), templateAuthor(c), comment)
 		if ErrorIf(c, err) {
 			logger.Error(err)
 			return
@@ -153,8 +305,9 @@ func startHook(r *gin.RouterGroup) {
 
 	r.POST("/hook/template/check", func(c *gin.Context) {
 		var err error
+		name, _ := c.GetPostForm("name")
 		content, _ := c.GetPostForm("content")
-		err = checkTemplate(content)
+		err = checkNamedTemplate(name, content)
 		if ErrorIf(c, err) {
 			logger.Error(err)
 			return
@@ -174,6 +327,36 @@ func startHook(r *gin.RouterGroup) {
 		Success(c, "ok")
 	})
 
+	r.GET("/hook/route", func(c *gin.Context) {
+		var labels = t.KV{}
+		for k, v := range c.Request.URL.Query() {
+			labels[k] = v[0]
+		}
+
+		route, targetNames := routeAlert(t.Alert{Labels: labels})
+		Success(c, gin.H{
+			"route":   route,
+			"targets": targetNames,
+			"matches": common.CONF.Webhook.MatchRoutes(labels),
+		})
+	})
+
+	r.GET("/hook/stats", func(c *gin.Context) {
+		stats := gin.H{
+			"queue":    getQueueStats(),
+			"template": getTemplateStats(),
+		}
+		if common.CONF.Webhook.DurableQueue.Enabled {
+			stored, err := model.CountHookQueues()
+			if ErrorIf(c, err) {
+				logger.Error(err)
+				return
+			}
+			stats["stored"] = stored
+		}
+		Success(c, stats)
+	})
+
 	r.GET("/hook/ignores", func(c *gin.Context) {
 		var err error
 		var params model.HookIgnore
@@ -187,6 +370,74 @@ func startHook(r *gin.RouterGroup) {
 		Success(c, lists)
 	})
 
+	r.GET("/hook/outbox", func(c *gin.Context) {
+		var err error
+		var params model.HookOutbox
+		var lists []model.HookOutbox
+
+		err = c.Bind(&params)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+
+		rowsValue, _ := c.GetQuery("rows")
+		limit := common.ParseInt(rowsValue)
+		if limit == 0 {
+			limit = 100
+		}
+
+		lists, err = params.GetList(limit)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, lists)
+	})
+
+	r.GET("/hook/deadletters", func(c *gin.Context) {
+		var err error
+		var params model.HookDeadLetter
+		var lists []model.HookDeadLetter
+
+		err = c.Bind(&params)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+
+		rowsValue, _ := c.GetQuery("rows")
+		limit := common.ParseInt(rowsValue)
+		if limit == 0 {
+			limit = 100
+		}
+
+		lists, err = params.GetList(limit)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, lists)
+	})
+
+	r.POST("/hook/deadletter/requeue", func(c *gin.Context) {
+		var err error
+		var params model.HookDeadLetter
+
+		err = c.Bind(&params)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+
+		err = requeueDeadLetter(&params)
+		if ErrorIf(c, err) {
+			logger.Error(err)
+			return
+		}
+		Success(c, "ok")
+	})
+
 	r.GET("/hook/alerts", func(c *gin.Context) {
 		var err error
 		var params model.Hook
@@ -234,185 +485,310 @@ func toPromAlert(o model.Notification) t.Alert {
 	return tmpAlert
 }
 
-func hookSender(chanHook chan t.Alert) {
-	var targets = common.CONF.Webhook.Targets
-	hookDefaultTemplate, _ = template.New("default_template").Parse(defaultTemplate)
+func hookSender(queue chan hookTask) {
+	hookDefaultTemplate, _ = parseTemplate("default_template", defaultTemplate)
 
+	hookWorkers.Add(1)
 	go func() {
+		defer hookWorkers.Done()
 		for {
-
-			alert := <-chanHook
-			target := targets[alert.Labels[labelLevel]]
-			api := target.API
-			apiParams := target.Params
-
-			// ============================================
-			// Generate fingerprint if fingerprint is empty
-			// ============================================
-			k := fmt.Sprintf("%d", alert.StartsAt.Unix())
-			k += alert.Labels[labelAlertname]
-			k += alert.Labels[labelInstance]
-			k += alert.Labels[labelJob]
-			k += alert.Labels[labelLevel]
-			hookID := crypt.MD5(k)
-
-			// ============================================
-			// Generate template variables
-			// ============================================
-			var vars = map[string]interface{}{}
-			for _, v := range common.CONF.Webhook.LabelMapper {
-				vars[v] = alert.Labels[v]
-			}
-			for _, v := range common.CONF.Webhook.AnnotationMapper {
-				vars[v] = alert.Annotations[v]
-			}
-			startsAt := alert.StartsAt.In(common.GetLocation())
-			endsAt := alert.EndsAt.In(common.GetLocation())
-			vars["startsAt"] = startsAt
-			vars["endsAt"] = endsAt
-			vars["status"] = alert.Status
-			logger.Debug("==>", vars)
-
-			// ============================================
-			// apply template, if error set default
-			// ============================================
-			var messageBuffer bytes.Buffer
-			if err := hookTemplate.Execute(&messageBuffer, vars); err != nil {
-				hookDefaultTemplate.Execute(&messageBuffer, vars)
+			// stop at shutdown timeout, left alerts are saved
+			select {
+			case <-quitWorkers:
+				return
+			default:
 			}
 
-			jsonMarshal, _ := json.Marshal(alert)
-			reqJSON := string(jsonMarshal)
-			message := messageBuffer.String()
-			hook := &model.Hook{
-				HookID:    hookID,
-				AlertName: alert.Labels["alertname"],
-				Instance:  alert.Labels[labelInstance],
-				Job:       alert.Labels[labelJob],
-				Level:     alert.Labels[labelLevel],
-				Ignored:   "N",
-				Status:    alert.Status,
-				StartsAt:  &startsAt,
-				EndsAt:    &endsAt,
-				HookDetails: []model.HookDetail{
-					{
-						HookID:  hookID,
-						Status:  alert.Status,
-						ReqJSON: string(reqJSON),
-						Message: messageBuffer.String(),
-					},
-				},
+			task, ok := <-queue
+			if !ok {
+				return
 			}
-			jsonMarshal, _ = json.Marshal(hook)
-
-			// ============================================
-			// Check ignore hook
-			// ============================================
-			logger.Debug("Check ignore hook")
-			hookIgnore := &model.HookIgnore{
-				Instance:  hook.Instance,
-				AlertName: hook.AlertName,
-				Status:    hook.Status,
+			if task.data != nil {
+				sendGroup(*task.data)
+			} else {
+				sendHook(task.alert)
 			}
 
-			if hookIgnore.IsTarget() {
-				hook.Ignored = "Y"
+			// processed, remove from durable queue
+			if task.queueID > 0 {
+				(&model.HookQueue{ID: task.queueID}).Delete()
 			}
+		}
+	}()
+}
 
-			// ============================================
-			// Save database
-			// ============================================
-			switch strings.ToLower(hook.Status) {
-			case "firing":
-				if hook.Job == "noti" {
-					hook.Status = "resolved"
-				} else {
-					hook.EndsAt = nil
-				}
-				if err := hook.Upsert("hook_id"); err != nil {
-					logger.Error("DB -", err.Error(), string(jsonMarshal))
-				}
-			case "resolved":
-				if err := hook.Upsert("status", "ends_at", "updated_at"); err != nil {
-					logger.Error("DB -", err.Error(), string(jsonMarshal))
-				}
-			default:
-				logger.Info("DB - skip status", hook.Status, string(jsonMarshal))
-				continue
-			}
+// sendHook save alert and send alarm to targets
+func sendHook(alert t.Alert) {
+	jsonMarshal, _ := json.Marshal(alert)
+	processHook(getHookID(alert), alert, alertVars(alert), string(jsonMarshal), false)
+}
 
-			if hook.Ignored == "Y" {
-				continue
-			}
+// processHook route, render, save and send alert, alert of group mode is made from common labels
+func processHook(hookID string, alert t.Alert, vars map[string]interface{}, reqJSON string, group bool) {
+	route, targetNames := routeAlert(alert)
+
+	// ============================================
+	// Generate template variables
+	// ============================================
+	startsAt := vars["startsAt"].(time.Time)
+	endsAt := vars["endsAt"].(time.Time)
+	logger.Debug("==>", vars)
+
+	// ============================================
+	// apply template of alertname or level
+	// ============================================
+	templateName := alertTemplateName(alert)
+	var templateErrors []string
+	message, err := renderMessage(templateName, vars)
+	if err != nil {
+		templateErrors = append(templateErrors, err.Error())
+	}
 
-			// ============================================
-			// Send alarm
-			// ============================================
-			httpClient := &http.Client{Timeout: 3 * time.Second}
-			urlencodedParams := strings.Replace(apiParams, "[[message]]", url.QueryEscape(strings.TrimSpace(message)), -1)
-			switch strings.ToUpper(target.Method) {
-			case "POST":
-				resp, err := httpClient.Post(api, "application/x-www-form-urlencoded", strings.NewReader(urlencodedParams))
-				if err != nil {
-					logger.Error("API -", err.Error(), string(jsonMarshal))
-					continue
-				}
-				if resp.StatusCode != 200 {
-					b, _ := ioutil.ReadAll(resp.Body)
-					logger.Error("API code -", resp.StatusCode, "-", string(b), string(jsonMarshal))
-					continue
-				}
-				defer resp.Body.Close()
-			case "GET":
-				resp, err := httpClient.Get(api + "?" + urlencodedParams)
-				if err != nil {
-					logger.Error("API -", err.Error(), string(jsonMarshal))
-					continue
-				}
-				if resp.StatusCode != 200 {
-					b, _ := ioutil.ReadAll(resp.Body)
-					logger.Error("API code -", resp.StatusCode, "-", string(b), string(jsonMarshal))
-					continue
-				}
-				defer resp.Body.Close()
-			default:
-				logger.Error("unsupport method -", target.Method, string(jsonMarshal))
+	// target template first
+	targetMessages := map[string]string{}
+	for _, targetName := range targetNames {
+		name := common.CONF.Webhook.Targets[targetName].Template
+		if name == "" || name == templateName {
+			continue
+		}
+		targetMessage, err := renderMessage(name, vars)
+		if err != nil {
+			templateErrors = append(templateErrors, err.Error())
+		}
+		targetMessages[targetName] = targetMessage
+	}
+
+	hook := &model.Hook{
+		HookID:    hookID,
+		AlertName: alert.Labels["alertname"],
+		Instance:  alert.Labels[labelInstance],
+		Job:       alert.Labels[labelJob],
+		Level:     alert.Labels[labelLevel],
+		Ignored:   "N",
+		Status:    alert.Status,
+		StartsAt:  &startsAt,
+		EndsAt:    &endsAt,
+		HookDetails: []model.HookDetail{
+			{
+				HookID:        hookID,
+				Status:        alert.Status,
+				ReqJSON:       reqJSON,
+				Message:       message,
+				Route:         route,
+				TemplateError: strings.Join(templateErrors, "\n"),
+			},
+		},
+	}
+	jsonMarshal, _ := json.Marshal(hook)
+
+	// ============================================
+	// Check ignore hook
+	// ============================================
+	logger.Debug("Check ignore hook")
+	hookIgnore := &model.HookIgnore{
+		Instance:  hook.Instance,
+		AlertName: hook.AlertName,
+		Status:    hook.Status,
+	}
+
+	if hookIgnore.IsTarget() {
+		hook.Ignored = "Y"
+	}
+
+	// ============================================
+	// Save database
+	// ============================================
+	switch strings.ToLower(hook.Status) {
+	case "firing":
+		if hook.Job == "noti" {
+			hook.Status = "resolved"
+		} else {
+			hook.EndsAt = nil
+		}
+	case "resolved":
+	default:
+		logger.Info("DB - skip status", hook.Status, string(jsonMarshal))
+		return
+	}
+	if err := hook.Upsert(hookColumns(alert.Status, group)...); err != nil {
+		logger.Error("DB -", err.Error(), string(jsonMarshal))
+	}
+
+	if hook.Ignored == "Y" {
+		return
+	}
+
+	// ============================================
+	// Send alarm to every target of route
+	// ============================================
+	if len(targetNames) == 0 {
+		logger.Error("API - no target for route '", route, "' ", string(jsonMarshal))
+		deadLetter := &model.HookDeadLetter{
+			HookID:       hookID,
+			HookDetailID: hook.HookDetails[0].ID,
+			Reason:       fmt.Sprintf("no target for route '%s'", route),
+			ReqJSON:      reqJSON,
+			Message:      message,
+		}
+		deadLetter.Insert()
+		return
+	}
+	notice := &Notice{
+		HookID:  hookID,
+		Alert:   alert,
+		Vars:    vars,
+		Message: message,
+	}
+	hookDetailID := hook.HookDetails[0].ID
+	var wg sync.WaitGroup
+	for _, targetName := range targetNames {
+		wg.Add(1)
+		targetNotice := notice
+		if targetMessage, ok := targetMessages[targetName]; ok {
+			n := *notice
+			n.Message = targetMessage
+			targetNotice = &n
+		}
+		go func(targetName string, notice *Notice) {
+			defer wg.Done()
+			sendTarget(hookDetailID, targetName, notice, reqJSON)
+		}(targetName, targetNotice)
+	}
+	wg.Wait()
+}
+
+// hookColumns columns updated for existing hook id, firing of group hook id starts a new episode
+// after resolved, so status and time range are updated too
+func hookColumns(status string, group bool) []string {
+	switch {
+	case strings.ToLower(status) == "resolved":
+		return []string{"status", "ends_at", "updated_at"}
+	case group:
+		return []string{"status", "starts_at", "ends_at", "updated_at"}
+	}
+	return []string{"hook_id"}
+}
+
+// routeAlert target names from route tree, then level targets, then default targets
+// matched routes without any target fall through to level targets
+func routeAlert(alert t.Alert) (route string, targetNames []string) {
+	var routes []string
+	var seen = map[string]bool{}
+	for _, match := range common.CONF.Webhook.MatchRoutes(alert.Labels) {
+		if len(match.Targets) == 0 {
+			continue
+		}
+		routes = append(routes, match.Route)
+		for _, name := range match.Targets {
+			if !seen[name] {
+				seen[name] = true
+				targetNames = append(targetNames, name)
 			}
 		}
-	}()
+	}
+	if len(targetNames) > 0 {
+		return strings.Join(routes, ","), targetNames
+	}
+
+	level := alert.Labels[labelLevel]
+	if targetNames = common.CONF.Webhook.GetTargetNames(level); len(targetNames) > 0 {
+		return "level:" + level, targetNames
+	}
+	return "default", common.CONF.Webhook.DefaultTargets
 }
 
-func loadTemplate(path ...string) (err error) {
-	if len(path) == 0 {
-		hookTemplate, _ = fileUtil.GetTemplate("template", defaultTemplate)
+// sendTarget deliver notice to target, save to outbox if failed
+func sendTarget(hookDetailID int, targetName string, notice *Notice, reqJSON string) {
+	target := common.CONF.Webhook.Targets[targetName]
+	err := deliver(hookDetailID, 1, targetName, target, notice)
+	if err == nil {
+		return
+	}
+	logger.Error("API - ", targetName, " - ", err.Error(), " ", reqJSON)
+
+	// retry later from outbox
+	outbox := &model.HookOutbox{
+		HookID:       notice.HookID,
+		HookDetailID: hookDetailID,
+		Target:       targetName,
+		ReqJSON:      reqJSON,
+		Message:      notice.Message,
+		Recipients:   failedRecipients(err),
+		LastError:    errorText(err),
+	}
+	if err = outbox.Insert(retryBackoff(1)); err != nil {
+		logger.Error("DB -", err.Error(), reqJSON)
 	}
-	logger.Info("open template file ", path)
-	hookTemplate, err = fileUtil.GetTemplate("template", fileUtil.ReadFile(path[0]))
-	return
 }
 
-func readTemplate(path ...string) (content string) {
-	if len(path) == 0 {
-		logger.Debug("get default template ")
-		content = defaultTemplate
-	} else {
-		logger.Debug("read template file ", path)
-		content = fileUtil.ReadFile(path[0])
+// deliver notify target and save delivery result
+func deliver(hookDetailID int, attempt int, targetName string, target common.WebhookTarget, notice *Notice) error {
+	var response Response
+	start := time.Now()
+	notifier, err := GetNotifier(target.Type)
+	if err == nil {
+		response, err = notifier.Notify(target, notice)
+	}
+
+	delivery := &model.HookDelivery{
+		HookDetailID: hookDetailID,
+		HookID:       notice.HookID,
+		Target:       targetName,
+		Attempt:      attempt,
+		Code:         response.Code,
+		Response:     truncate(response.Body, 1000),
+		DurationMs:   time.Since(start).Milliseconds(),
 	}
-	return
+	if err != nil {
+		delivery.Error = errorText(err)
+	}
+	delivery.Insert()
+	return err
+}
+
+// getHookID hook id from start time and alert labels
+func getHookID(alert t.Alert) string {
+	k := fmt.Sprintf("%d", alert.StartsAt.Unix())
+	k += alert.Labels[labelAlertname]
+	k += alert.Labels[labelInstance]
+	k += alert.Labels[labelJob]
+	k += alert.Labels[labelLevel]
+	return crypt.MD5(k)
 }
 
-func checkTemplate(content string) (err error) {
-	if strings.TrimSpace(content) == "" {
-		return fmt.Errorf("template content is null")
+// alertVars template variables of alert
+func alertVars(alert t.Alert) map[string]interface{} {
+	var vars = map[string]interface{}{}
+	for _, v := range common.CONF.Webhook.LabelMapper {
+		vars[v] = alert.Labels[v]
 	}
-	_, err = fileUtil.GetTemplate("template", content)
-	return
+	for _, v := range common.CONF.Webhook.AnnotationMapper {
+		vars[v] = alert.Annotations[v]
+	}
+	vars["startsAt"] = alert.StartsAt.In(common.GetLocation())
+	vars["endsAt"] = alert.EndsAt.In(common.GetLocation())
+	vars["status"] = alert.Status
+
+	// all labels and annotations, not only mapped
+	vars["labels"] = alert.Labels
+	vars["annotations"] = alert.Annotations
+	vars["generatorURL"] = alert.GeneratorURL
+	vars["fingerprint"] = alert.Fingerprint
+	vars["duration"] = alertDuration(alert)
+	return vars
 }
 
-func writeTemplate(path string, content string) (err error) {
-	if err = checkTemplate(content); err == nil {
-		err = ioutil.WriteFile(path, []byte(content), 0644)
+// alertDuration firing time until now, or until resolved
+func alertDuration(alert t.Alert) time.Duration {
+	if alert.StartsAt.IsZero() {
+		return 0
+	}
+	end := time.Now()
+	if strings.ToLower(alert.Status) == "resolved" && !alert.EndsAt.IsZero() {
+		end = alert.EndsAt
+	}
+	if end.Before(alert.StartsAt) {
+		return 0
 	}
-	return
+	return end.Sub(alert.StartsAt).Truncate(time.Second)
 }
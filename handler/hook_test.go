@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+	"github.com/go-gywn/webhook-go/model"
+)
+
+func TestRouteAlert(t *testing.T) {
+	defaultWebhook := common.CONF.Webhook
+	defer func() { common.CONF.Webhook = defaultWebhook }()
+
+	conf := &common.CONF.Webhook
+	conf.Targets = map[string]common.WebhookTarget{"dba": {}, "sre": {}, "sms": {}, "slack": {}, "fallback": {}}
+	conf.Levels = map[string][]string{"warning": {"slack"}}
+	conf.DefaultTargets = []string{"fallback"}
+	conf.Routes = []common.Route{
+		{
+			Name:     "db",
+			Matchers: []string{"job=mysql"},
+			Targets:  []string{"dba"},
+			Routes:   []common.Route{{Name: "critical", Matchers: []string{"level=critical"}, Targets: []string{"dba", "sms"}}},
+		},
+		{Name: "web", Matchers: []string{"job=nginx"}},
+		{Name: "node", Matchers: []string{"job=node"}, Targets: []string{"sre"}, Continue: true},
+		{Name: "all-node", Matchers: []string{"job=~node.*"}, Targets: []string{"sre", "slack"}},
+	}
+	if err := conf.CompileRoutes(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		job         string
+		level       string
+		wantRoute   string
+		wantTargets []string
+	}{
+		{name: "child route", job: "mysql", level: "critical", wantRoute: "db/critical", wantTargets: []string{"dba", "sms"}},
+		{name: "parent route", job: "mysql", level: "warning", wantRoute: "db", wantTargets: []string{"dba"}},
+		{name: "continue routes without duplicate target", job: "node", level: "critical", wantRoute: "node,all-node", wantTargets: []string{"sre", "slack"}},
+		{name: "route without targets to level targets", job: "nginx", level: "warning", wantRoute: "level:warning", wantTargets: []string{"slack"}},
+		{name: "no route to target named as level", job: "redis", level: "sms", wantRoute: "level:sms", wantTargets: []string{"sms"}},
+		{name: "route without targets to default targets", job: "nginx", level: "critical", wantRoute: "default", wantTargets: []string{"fallback"}},
+		{name: "no route to default targets", job: "redis", level: "info", wantRoute: "default", wantTargets: []string{"fallback"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := testAlert("firing")
+			alert.Labels[labelJob] = tt.job
+			alert.Labels[labelLevel] = tt.level
+
+			route, targets := routeAlert(alert)
+			if route != tt.wantRoute || !reflect.DeepEqual(targets, tt.wantTargets) {
+				t.Errorf("routeAlert() = %s %v, want %s %v", route, targets, tt.wantRoute, tt.wantTargets)
+			}
+		})
+	}
+}
+
+func TestHookColumnsGroupEpisode(t *testing.T) {
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	end, restart := start.Add(30*time.Minute), start.Add(time.Hour)
+	notifications := []model.Hook{
+		{Status: "firing", StartsAt: &start},
+		{Status: "resolved", StartsAt: &start, EndsAt: &end},
+		{Status: "firing", StartsAt: &restart},
+	}
+
+	// hook row after upsert of every notification of a group
+	var row *model.Hook
+	for i := range notifications {
+		hook := notifications[i]
+		if row == nil {
+			row = &hook
+			continue
+		}
+		for _, column := range hookColumns(hook.Status, true) {
+			switch column {
+			case "status":
+				row.Status = hook.Status
+			case "starts_at":
+				row.StartsAt = hook.StartsAt
+			case "ends_at":
+				row.EndsAt = hook.EndsAt
+			}
+		}
+	}
+	if row.Status != "firing" || !row.StartsAt.Equal(restart) || row.EndsAt != nil {
+		t.Errorf("hook = %s %v %v, want firing %v <nil>", row.Status, row.StartsAt, row.EndsAt, restart)
+	}
+}
+
+func TestHookColumns(t *testing.T) {
+	tests := []struct {
+		status string
+		group  bool
+		want   []string
+	}{
+		{status: "firing", want: []string{"hook_id"}},
+		{status: "resolved", want: []string{"status", "ends_at", "updated_at"}},
+		{status: "firing", group: true, want: []string{"status", "starts_at", "ends_at", "updated_at"}},
+		{status: "resolved", group: true, want: []string{"status", "ends_at", "updated_at"}},
+	}
+	for _, tt := range tests {
+		if got := hookColumns(tt.status, tt.group); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("hookColumns(%s, %v) = %v, want %v", tt.status, tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestAlertVars(t *testing.T) {
+	defaultWebhook := common.CONF.Webhook
+	defer func() { common.CONF.Webhook = defaultWebhook }()
+	common.CONF.Webhook.LabelMapper = map[string]string{"alertname": "alertname", "instance": "instance"}
+	common.CONF.Webhook.AnnotationMapper = map[string]string{"summary": "summary"}
+
+	alert := testAlert("resolved")
+	alert.Labels["team"] = "dba"
+	vars := alertVars(alert)
+
+	want := map[string]interface{}{
+		"alertname":    "MySQLDown",
+		"instance":     "db1:3306",
+		"summary":      "mysql is down",
+		"status":       "resolved",
+		"generatorURL": "http://prometheus:9090/graph",
+		"fingerprint":  "0123456789abcdef",
+		"duration":     10 * time.Minute,
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+		}
+	}
+	for _, k := range []string{"job", "level", "description"} {
+		if _, ok := vars[k]; ok {
+			t.Errorf("vars[%s] is set without mapper", k)
+		}
+	}
+	// all labels and annotations, not only mapped
+	if !reflect.DeepEqual(vars["labels"], alert.Labels) {
+		t.Errorf("vars[labels] = %v, want %v", vars["labels"], alert.Labels)
+	}
+	if !reflect.DeepEqual(vars["annotations"], alert.Annotations) {
+		t.Errorf("vars[annotations] = %v, want %v", vars["annotations"], alert.Annotations)
+	}
+}
+
+func TestAlertDuration(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		status   string
+		startsAt time.Time
+		endsAt   time.Time
+		min, max time.Duration
+	}{
+		{name: "no start", status: "firing"},
+		{name: "firing until now", status: "firing", startsAt: now.Add(-90 * time.Second), min: 90 * time.Second, max: 92 * time.Second},
+		{name: "firing ends at is ignored", status: "firing", startsAt: now.Add(-time.Minute), endsAt: now.Add(time.Hour), min: time.Minute, max: 62 * time.Second},
+		{name: "resolved until ends at", status: "resolved", startsAt: now.Add(-time.Hour), endsAt: now.Add(-30*time.Minute + 500*time.Millisecond), min: 30 * time.Minute, max: 30 * time.Minute},
+		{name: "resolved without ends at", status: "resolved", startsAt: now.Add(-time.Minute), min: time.Minute, max: 62 * time.Second},
+		{name: "start in future", status: "firing", startsAt: now.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := testAlert(tt.status)
+			alert.StartsAt, alert.EndsAt = tt.startsAt, tt.endsAt
+			if got := alertDuration(alert); got < tt.min || got > tt.max {
+				t.Errorf("alertDuration() = %v, want %v ~ %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
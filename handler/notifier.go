@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+	t "github.com/prometheus/alertmanager/template"
+)
+
+// Notice rendered alert to deliver, recipients limit target recipients at retry
+type Notice struct {
+	HookID     string
+	Alert      t.Alert
+	Vars       map[string]interface{}
+	Message    string
+	Recipients []string
+}
+
+// RecipientError delivery failed for some recipients, only failed recipients are retried
+type RecipientError struct {
+	Failed []string
+	Err    error
+}
+
+func (e *RecipientError) Error() string {
+	return e.Err.Error()
+}
+
+// failedRecipients recipients to retry, empty means all
+func failedRecipients(err error) string {
+	var recipientErr *RecipientError
+	if errors.As(err, &recipientErr) {
+		return strings.Join(recipientErr.Failed, ",")
+	}
+	return ""
+}
+
+// splitRecipients saved recipients to notice recipients
+func splitRecipients(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// Response target response of delivery
+type Response struct {
+	Code int
+	Body string
+}
+
+// Notifier deliver notice to webhook target
+type Notifier interface {
+	Notify(target common.WebhookTarget, notice *Notice) (Response, error)
+}
+
+var notifierMtx = &sync.RWMutex{}
+var notifiers = map[string]Notifier{}
+
+// httpClient shared client for http based notifiers
+var httpClient = &http.Client{Timeout: 3 * time.Second}
+
+// RegisterNotifier register notifier for target type
+func RegisterNotifier(targetType string, notifier Notifier) {
+	notifierMtx.Lock()
+	defer notifierMtx.Unlock()
+	notifiers[strings.ToLower(targetType)] = notifier
+}
+
+// GetNotifier get notifier for target type, empty type is "http"
+func GetNotifier(targetType string) (Notifier, error) {
+	notifierMtx.RLock()
+	defer notifierMtx.RUnlock()
+
+	if targetType == "" {
+		targetType = "http"
+	}
+	notifier, ok := notifiers[strings.ToLower(targetType)]
+	if !ok {
+		return nil, fmt.Errorf("unsupport target type - %s", targetType)
+	}
+	return notifier, nil
+}
+
+// doRequest apply target headers, auth and signature, then send and check response code
+func doRequest(target common.WebhookTarget, notice *Notice, req *http.Request) (Response, error) {
+	if err := setRequestHeaders(target, notice, req); err != nil {
+		return Response{}, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Response{}, stripURL(err)
+	}
+	defer resp.Body.Close()
+
+	// response body is saved in delivery, not in error
+	b, _ := ioutil.ReadAll(resp.Body)
+	response := Response{Code: resp.StatusCode, Body: string(b)}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return response, fmt.Errorf("response code %d", resp.StatusCode)
+	}
+	return response, nil
+}
+
+// stripURL error without request url, url can have secret like bot token or webhook path
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed - %s", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// setRequestHeaders header values are templates with notice vars
+func setRequestHeaders(target common.WebhookTarget, notice *Notice, req *http.Request) error {
+	for k, v := range target.Headers {
+		tpl, err := getTargetTemplate(v)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err = tpl.Execute(&buf, notice.Vars); err != nil {
+			return err
+		}
+		req.Header.Set(k, strings.TrimSpace(buf.String()))
+	}
+
+	auth := target.Auth
+	secret := func(s string) string {
+		if auth.Encrypted {
+			return crypt.DecryptAES(s)
+		}
+		return s
+	}
+	switch strings.ToLower(auth.Type) {
+	case "":
+	case "basic":
+		req.SetBasicAuth(auth.User, secret(auth.Pass))
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+secret(auth.Token))
+	default:
+		return fmt.Errorf("unsupport auth type - %s", auth.Type)
+	}
+
+	if target.Signature.Secret != "" {
+		return signRequest(target.Signature, req)
+	}
+	return nil
+}
+
+// signRequest HMAC-SHA256 hex of "timestamp.body" as "sha256=<hex>"
+func signRequest(sign common.TargetSignature, req *http.Request) error {
+	var body []byte
+	if req.GetBody != nil {
+		r, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		if body, err = ioutil.ReadAll(r); err != nil {
+			return err
+		}
+	}
+
+	key := sign.Secret
+	if sign.Encrypted {
+		key = crypt.DecryptAES(key)
+	}
+	header := sign.Header
+	if header == "" {
+		header = "X-Webhook-Signature"
+	}
+	timestampHeader := sign.TimestampHeader
+	if timestampHeader == "" {
+		timestampHeader = "X-Webhook-Timestamp"
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+// mappedPairs mapper name and value of mapped key, sorted by name without empty value
+func mappedPairs(mapper map[string]string, kv t.KV) t.Pairs {
+	var pairs t.Pairs
+	for name, key := range mapper {
+		if value := kv[key]; value != "" {
+			pairs = append(pairs, t.Pair{Name: name, Value: value})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+// maxErrorLength error text length to save
+const maxErrorLength = 1000
+
+// errorText error to save, cut to maxErrorLength
+func errorText(err error) string {
+	return truncate(err.Error(), maxErrorLength)
+}
+
+// truncate cut string to max runes, no ellipsis if max is less than 3
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	if max < 3 {
+		if max < 0 {
+			max = 0
+		}
+		return string(r[:max])
+	}
+	return string(r[:max-3]) + "..."
+}
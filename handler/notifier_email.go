@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+// emailNotifier smtp mail with text/plain and text/html body
+type emailNotifier struct{}
+
+var emailDefaultSubject = `[{{ .status }}] {{ .summary }}`
+var emailTimeout = 10 * time.Second
+
+func init() {
+	RegisterNotifier("email", &emailNotifier{})
+}
+
+// Notify send mail to smtp host, response is smtp 250 if the mail is accepted
+func (o *emailNotifier) Notify(target common.WebhookTarget, notice *Notice) (Response, error) {
+	opt := target.Email
+	if opt.Host == "" || opt.From == "" || len(opt.To) == 0 {
+		return Response{}, fmt.Errorf("email host, from and to must be set")
+	}
+
+	subject, err := o.subject(opt, notice)
+	if err != nil {
+		return Response{}, err
+	}
+
+	msg, err := o.message(opt, subject, notice.Message)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err = o.send(opt, msg); err != nil {
+		return Response{}, err
+	}
+	return Response{Code: 250, Body: "ok"}, nil
+}
+
+// subject render subject template with notice vars
+func (o *emailNotifier) subject(opt common.EmailTarget, notice *Notice) (string, error) {
+	content := opt.Subject
+	if content == "" {
+		content = emailDefaultSubject
+	}
+	tpl, err := parseTemplate("email_subject", content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, notice.Vars); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.Replace(buf.String(), "\n", " ", -1)), nil
+}
+
+// message build multipart/alternative mail
+func (o *emailNotifier) message(opt common.EmailTarget, subject string, message string) ([]byte, error) {
+	boundary, err := emailBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", opt.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(opt.To, ", "))
+	if len(opt.Cc) > 0 {
+		fmt.Fprintf(&buf, "Cc: %s\r\n", strings.Join(opt.Cc, ", "))
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
+
+	htmlBody := "<html><body><pre style=\"font-family: monospace\">" + html.EscapeString(message) + "</pre></body></html>"
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", message},
+		{"text/html; charset=utf-8", htmlBody},
+	}
+	for _, part := range parts {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", part.contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err = qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		qp.Close()
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// send deliver mail, tls is "" (plain), "starttls" or "tls" (implicit)
+func (o *emailNotifier) send(opt common.EmailTarget, msg []byte) error {
+	hostname, _, err := net.SplitHostPort(opt.Host)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{ServerName: hostname, InsecureSkipVerify: opt.InsecureSkipVerify}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: emailTimeout}
+	switch strings.ToLower(opt.TLS) {
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", opt.Host, tlsConfig)
+	case "", "starttls":
+		conn, err = dialer.Dial("tcp", opt.Host)
+	default:
+		return fmt.Errorf("unsupport email tls - %s", opt.TLS)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+
+	c, err := smtp.NewClient(conn, hostname)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if strings.ToLower(opt.TLS) == "starttls" {
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if opt.User != "" {
+		pass := opt.Pass
+		if opt.Encrypted {
+			pass = crypt.DecryptAES(pass)
+		}
+		auth := smtp.PlainAuth("", opt.User, pass, hostname)
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	from, err := mail.ParseAddress(opt.From)
+	if err != nil {
+		return err
+	}
+	if err = c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range append(append([]string{}, opt.To...), opt.Cc...) {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return err
+		}
+		if err = c.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func emailBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
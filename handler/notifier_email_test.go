@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+// testSMTP in-process smtp server, startTLS is offered if tlsConfig is set, AUTH PLAIN if user is set
+type testSMTP struct {
+	addr      string
+	tlsConfig *tls.Config
+	implicit  bool
+	user      string
+	pass      string
+
+	mtx      sync.Mutex
+	tls      bool
+	authUser string
+	from     string
+	rcpts    []string
+	data     []byte
+	done     chan struct{}
+}
+
+func newTestSMTP(tb testing.TB, s *testSMTP) *testSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if s.implicit {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	tb.Cleanup(func() { listener.Close() })
+
+	s.addr = listener.Addr().String()
+	s.done = make(chan struct{})
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer close(s.done)
+		s.serve(conn)
+	}()
+	return s
+}
+
+// serve one smtp session
+func (s *testSMTP) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	isTLS := s.implicit
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 127.0.0.1 ESMTP test")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.SplitN(line, " ", 2)
+		cmd, arg := strings.ToUpper(fields[0]), ""
+		if len(fields) > 1 {
+			arg = fields[1]
+		}
+
+		switch cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-127.0.0.1")
+			if s.tlsConfig != nil && !isTLS {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			if s.user != "" {
+				tp.PrintfLine("250-AUTH PLAIN")
+			}
+			tp.PrintfLine("250 8BITMIME")
+		case "STARTTLS":
+			if s.tlsConfig == nil || isTLS {
+				tp.PrintfLine("502 not supported")
+				continue
+			}
+			tp.PrintfLine("220 ready to start tls")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err = tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, isTLS = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			b, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			credentials := strings.Split(string(b), "\x00")
+			if len(credentials) != 3 || credentials[1] != s.user || credentials[2] != s.pass {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			s.mtx.Lock()
+			s.authUser = credentials[1]
+			s.mtx.Unlock()
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			s.mtx.Lock()
+			s.from = smtpPath(arg)
+			s.tls = isTLS
+			s.mtx.Unlock()
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.mtx.Lock()
+			s.rcpts = append(s.rcpts, smtpPath(arg))
+			s.mtx.Unlock()
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 end with .")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mtx.Lock()
+			s.data = data
+			s.mtx.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+// smtpPath address in angle brackets of MAIL and RCPT argument
+func smtpPath(arg string) string {
+	start, end := strings.Index(arg, "<"), strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+// wait session end
+func (s *testSMTP) wait(tb testing.TB) {
+	tb.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		tb.Fatal("smtp session not finished")
+	}
+}
+
+// testTLSConfig self-signed certificate for 127.0.0.1
+func testTLSConfig(tb testing.TB) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	tlsConfig := testTLSConfig(t)
+	tests := []struct {
+		name      string
+		server    *testSMTP
+		tls       string
+		user      string
+		encrypted bool
+	}{
+		{name: "plain", server: &testSMTP{}},
+		{name: "plain auth", server: &testSMTP{user: "webhook", pass: "secret"}, user: "webhook"},
+		{name: "plain auth encrypted", server: &testSMTP{user: "webhook", pass: "secret"}, user: "webhook", encrypted: true},
+		{name: "starttls", server: &testSMTP{tlsConfig: tlsConfig}, tls: "starttls"},
+		{name: "starttls auth", server: &testSMTP{tlsConfig: tlsConfig, user: "webhook", pass: "secret"}, tls: "starttls", user: "webhook", encrypted: true},
+		{name: "implicit tls", server: &testSMTP{tlsConfig: tlsConfig, implicit: true}, tls: "tls"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestSMTP(t, tt.server)
+			target := common.WebhookTarget{
+				Type: "email",
+				Email: common.EmailTarget{
+					Host:               server.addr,
+					TLS:                tt.tls,
+					InsecureSkipVerify: true,
+					From:               "Webhook <webhook@example.com>",
+					To:                 []string{"dba@example.com", "Ops Team <ops@example.com>"},
+					Cc:                 []string{"sre@example.com"},
+				},
+			}
+			if tt.user != "" {
+				target.Email.User = tt.user
+				target.Email.Pass = "secret"
+			}
+			if tt.encrypted {
+				target.Email.Pass = crypt.EncryptAES("secret")
+				target.Email.Encrypted = true
+			}
+
+			resp, err := (&emailNotifier{}).Notify(target, testNotice("firing"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != 250 {
+				t.Errorf("code = %d, want 250", resp.Code)
+			}
+			server.wait(t)
+
+			server.mtx.Lock()
+			defer server.mtx.Unlock()
+			if server.tls != (tt.tls != "") {
+				t.Errorf("tls = %v, want %v", server.tls, tt.tls != "")
+			}
+			if server.authUser != tt.user {
+				t.Errorf("auth user = %q, want %q", server.authUser, tt.user)
+			}
+			if server.from != "webhook@example.com" {
+				t.Errorf("mail from = %s, want webhook@example.com", server.from)
+			}
+			if want := []string{"dba@example.com", "ops@example.com", "sre@example.com"}; !reflect.DeepEqual(server.rcpts, want) {
+				t.Errorf("rcpt to = %v, want %v", server.rcpts, want)
+			}
+			checkEmailMessage(t, server.data)
+		})
+	}
+}
+
+// checkEmailMessage headers and multipart text/html body of test notice
+func checkEmailMessage(t *testing.T, data []byte) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "[firing] mysql is down" {
+		t.Errorf("subject = %q, want [firing] mysql is down - %v", subject, err)
+	}
+	if got := msg.Header.Get("To"); got != "dba@example.com, Ops Team <ops@example.com>" {
+		t.Errorf("To = %s", got)
+	}
+	if got := msg.Header.Get("Cc"); got != "sre@example.com" {
+		t.Errorf("Cc = %s", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %s - %v", mediaType, err)
+	}
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		b, _ := ioutil.ReadAll(part)
+		parts[partType] = string(b)
+	}
+
+	if got := parts["text/plain"]; got != "mysql is down\ndb1 & db2 <critical>\n" {
+		t.Errorf("text/plain = %q", got)
+	}
+	if got := parts["text/html"]; !strings.Contains(got, "<pre") || !strings.Contains(got, "db1 &amp; db2 &lt;critical&gt;") {
+		t.Errorf("text/html = %q", got)
+	}
+}
+
+func TestEmailNotifierInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		email common.EmailTarget
+	}{
+		{name: "no host", email: common.EmailTarget{From: "webhook@example.com", To: []string{"dba@example.com"}}},
+		{name: "no to", email: common.EmailTarget{Host: "127.0.0.1:25", From: "webhook@example.com"}},
+		{name: "unknown tls", email: common.EmailTarget{Host: "127.0.0.1:25", TLS: "ssl", From: "webhook@example.com", To: []string{"dba@example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (&emailNotifier{}).Notify(common.WebhookTarget{Type: "email", Email: tt.email}, testNotice("firing")); err == nil {
+				t.Error("error is nil")
+			}
+		})
+	}
+}
+
+func TestEmailNotifierAuthFailed(t *testing.T) {
+	server := newTestSMTP(t, &testSMTP{user: "webhook", pass: "secret"})
+	target := common.WebhookTarget{
+		Type: "email",
+		Email: common.EmailTarget{
+			Host: server.addr,
+			User: "webhook",
+			Pass: "wrong",
+			From: "webhook@example.com",
+			To:   []string{"dba@example.com"},
+		},
+	}
+	if _, err := (&emailNotifier{}).Notify(target, testNotice("firing")); err == nil {
+		t.Fatal("error is nil with wrong password")
+	}
+
+	server.mtx.Lock()
+	defer server.mtx.Unlock()
+	if server.data != nil {
+		t.Error("mail is sent with wrong password")
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+// httpNotifier generic http target, form-encoded params or body template rendered with vars and message
+type httpNotifier struct{}
+
+var targetTemplates = &sync.Map{}
+
+func init() {
+	RegisterNotifier("http", &httpNotifier{})
+}
+
+// Notify send request to target api, method is POST if empty
+func (o *httpNotifier) Notify(target common.WebhookTarget, notice *Notice) (Response, error) {
+	var req *http.Request
+	var err error
+
+	method := strings.ToUpper(target.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	if target.Body != "" {
+		req, err = o.bodyRequest(method, target, notice)
+	} else {
+		req, err = o.formRequest(method, target, notice)
+	}
+	if err != nil {
+		return Response{}, stripURL(err)
+	}
+	return doRequest(target, notice, req)
+}
+
+// formRequest params as query string for GET, request body for others
+func (o *httpNotifier) formRequest(method string, target common.WebhookTarget, notice *Notice) (*http.Request, error) {
+	urlencodedParams := strings.Replace(target.Params, "[[message]]", url.QueryEscape(strings.TrimSpace(notice.Message)), -1)
+	if method == http.MethodGet {
+		return http.NewRequest(method, target.API+"?"+urlencodedParams, nil)
+	}
+
+	req, err := http.NewRequest(method, target.API, strings.NewReader(urlencodedParams))
+	if err != nil {
+		return nil, err
+	}
+	contentType := target.ContentType
+	if contentType == "" {
+		contentType = "application/x-www-form-urlencoded"
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+// bodyRequest render body template, content type is application/json if empty
+func (o *httpNotifier) bodyRequest(method string, target common.WebhookTarget, notice *Notice) (*http.Request, error) {
+	tpl, err := getTargetTemplate(target.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var data = map[string]interface{}{}
+	for k, v := range notice.Vars {
+		data[k] = v
+	}
+	data["message"] = strings.TrimSpace(notice.Message)
+
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, target.API, &buf)
+	if err != nil {
+		return nil, err
+	}
+	contentType := target.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+// getTargetTemplate parse body or header template once per content
+func getTargetTemplate(content string) (*template.Template, error) {
+	if v, ok := targetTemplates.Load(content); ok {
+		return v.(*template.Template), nil
+	}
+	tpl, err := template.New("target").Funcs(templateFuncs).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	targetTemplates.Store(content, tpl)
+	return tpl, nil
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestHTTPNotifierForm(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		wantMethod  string
+		wantType    string
+	}{
+		{name: "default post", wantMethod: http.MethodPost, wantType: "application/x-www-form-urlencoded"},
+		{name: "put with content type", method: "put", contentType: "text/plain", wantMethod: http.MethodPut, wantType: "text/plain"},
+		{name: "get", method: "GET", wantMethod: http.MethodGet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			target := common.WebhookTarget{
+				API:         server.URL + "/send",
+				Params:      "id=123&message=[[message]]",
+				Method:      tt.method,
+				ContentType: tt.contentType,
+			}
+			if _, err := (&httpNotifier{}).Notify(target, testNotice("firing")); err != nil {
+				t.Fatal(err)
+			}
+
+			req := server.only(t)
+			if req.Method != tt.wantMethod {
+				t.Errorf("method = %s, want %s", req.Method, tt.wantMethod)
+			}
+			if req.Path != "/send" {
+				t.Errorf("path = %s, want /send", req.Path)
+			}
+
+			params := req.Query
+			if tt.wantMethod != http.MethodGet {
+				if got := req.Header.Get("Content-Type"); got != tt.wantType {
+					t.Errorf("content type = %s, want %s", got, tt.wantType)
+				}
+				var err error
+				if params, err = url.ParseQuery(string(req.Body)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if params.Get("id") != "123" || params.Get("message") != "mysql is down\ndb1 & db2 <critical>" {
+				t.Errorf("params = %v", params)
+			}
+		})
+	}
+}
+
+func TestHTTPNotifierErrorResponse(t *testing.T) {
+	server := newTestServer(t)
+	server.reply = func(r testRequest) int { return http.StatusBadGateway }
+
+	target := common.WebhookTarget{API: server.URL, Params: "message=[[message]]"}
+	resp, err := (&httpNotifier{}).Notify(target, testNotice("firing"))
+	if err == nil {
+		t.Fatal("error is nil for 502 response")
+	}
+	if resp.Code != http.StatusBadGateway {
+		t.Errorf("code = %d, want 502", resp.Code)
+	}
+}
+
+func TestHTTPNotifierErrorWithoutURL(t *testing.T) {
+	server := newTestServer(t)
+	api := server.URL + "/bot-secret-token"
+	server.Close()
+
+	target := common.WebhookTarget{API: api, Params: "message=[[message]]"}
+	_, err := (&httpNotifier{}).Notify(target, testNotice("firing"))
+	if err == nil {
+		t.Fatal("error is nil for closed server")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error has request url - %s", err)
+	}
+}
+
+func TestHTTPNotifierBody(t *testing.T) {
+	server := newTestServer(t)
+	target := common.WebhookTarget{
+		API: server.URL,
+		Body: `{"title": {{ json .summary }}, "instance": "{{ jsonEscape .instance }}", ` +
+			`"labels": {{ toJSON .labels }}, "message": {{ json .message }}}`,
+		Headers: map[string]string{"X-Alert": "{{ .alertname }}-{{ .level | toUpper }}"},
+		Auth:    common.TargetAuth{Type: "bearer", Token: "secret-token"},
+	}
+	if _, err := (&httpNotifier{}).Notify(target, testNotice("firing")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := server.only(t)
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.Method)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("content type = %s, want application/json", got)
+	}
+	if got := req.Header.Get("X-Alert"); got != "MySQLDown-CRITICAL" {
+		t.Errorf("X-Alert = %s, want MySQLDown-CRITICAL", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret-token" {
+		t.Errorf("Authorization = %s, want Bearer secret-token", got)
+	}
+
+	body := decodeBody(t, req)
+	if body["title"] != "mysql is down" || body["instance"] != "db1:3306" {
+		t.Errorf("body = %v", body)
+	}
+	if body["message"] != "mysql is down\ndb1 & db2 <critical>" {
+		t.Errorf("message = %q", body["message"])
+	}
+	if labels, _ := body["labels"].(map[string]interface{}); labels[labelJob] != "mysql" {
+		t.Errorf("labels = %v", body["labels"])
+	}
+}
+
+func TestHTTPNotifierBasicAuth(t *testing.T) {
+	server := newTestServer(t)
+	target := common.WebhookTarget{
+		API:    server.URL,
+		Params: "message=[[message]]",
+		Auth:   common.TargetAuth{Type: "basic", User: "webhook", Pass: "pass"},
+	}
+	if _, err := (&httpNotifier{}).Notify(target, testNotice("firing")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &http.Request{Header: server.only(t).Header}
+	user, pass, ok := req.BasicAuth()
+	if !ok || user != "webhook" || pass != "pass" {
+		t.Errorf("basic auth = %s:%s %v, want webhook:pass", user, pass, ok)
+	}
+}
+
+func TestHTTPNotifierSignature(t *testing.T) {
+	tests := []struct {
+		name            string
+		sign            common.TargetSignature
+		header          string
+		timestampHeader string
+	}{
+		{
+			name:            "default headers",
+			sign:            common.TargetSignature{Secret: "hmac-secret"},
+			header:          "X-Webhook-Signature",
+			timestampHeader: "X-Webhook-Timestamp",
+		},
+		{
+			name:            "custom headers",
+			sign:            common.TargetSignature{Secret: "hmac-secret", Header: "X-Sign", TimestampHeader: "X-Time"},
+			header:          "X-Sign",
+			timestampHeader: "X-Time",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			target := common.WebhookTarget{
+				API:       server.URL,
+				Body:      `{"message": {{ json .message }}}`,
+				Signature: tt.sign,
+			}
+			if _, err := (&httpNotifier{}).Notify(target, testNotice("firing")); err != nil {
+				t.Fatal(err)
+			}
+
+			req := server.only(t)
+			timestamp := req.Header.Get(tt.timestampHeader)
+			if timestamp == "" {
+				t.Fatalf("%s is empty", tt.timestampHeader)
+			}
+			mac := hmac.New(sha256.New, []byte("hmac-secret"))
+			mac.Write([]byte(timestamp + "."))
+			mac.Write(req.Body)
+			want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+			if got := req.Header.Get(tt.header); got != want {
+				t.Errorf("%s = %s, want %s", tt.header, got, want)
+			}
+		})
+	}
+}
+
+func TestHTTPNotifierErrorWithoutBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("<html>" + strings.Repeat("error page ", 10000) + "</html>"))
+	}))
+	defer server.Close()
+
+	target := common.WebhookTarget{API: server.URL, Params: "message=[[message]]"}
+	resp, err := (&httpNotifier{}).Notify(target, testNotice("firing"))
+	if err == nil || err.Error() != "response code 500" {
+		t.Fatalf("error = %v, want response code 500", err)
+	}
+	if !strings.Contains(resp.Body, "error page") {
+		t.Errorf("response body = %.50q", resp.Body)
+	}
+}
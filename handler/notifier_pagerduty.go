@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+// pagerDutyNotifier pagerduty events api v2, hook id is dedup key
+type pagerDutyNotifier struct{}
+
+var pagerDutyAPI = "https://events.pagerduty.com/v2/enqueue"
+var pagerDutySeverities = map[string]bool{"critical": true, "error": true, "warning": true, "info": true}
+
+func init() {
+	RegisterNotifier("pagerduty", &pagerDutyNotifier{})
+}
+
+// Notify trigger on firing, resolve on resolved
+func (o *pagerDutyNotifier) Notify(target common.WebhookTarget, notice *Notice) (Response, error) {
+	if target.PagerDuty.RoutingKey == "" {
+		return Response{}, fmt.Errorf("pagerduty routing key must be set")
+	}
+
+	event, err := o.event(target, notice)
+	if err != nil {
+		return Response{}, err
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return Response{}, err
+	}
+
+	api := target.API
+	if api == "" {
+		api = pagerDutyAPI
+	}
+	req, err := http.NewRequest(http.MethodPost, api, bytes.NewReader(b))
+	if err != nil {
+		return Response{}, stripURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(target, notice, req)
+}
+
+// event build pagerduty event
+func (o *pagerDutyNotifier) event(target common.WebhookTarget, notice *Notice) (map[string]interface{}, error) {
+	alert := notice.Alert
+	event := map[string]interface{}{
+		"routing_key": target.PagerDuty.RoutingKey,
+		"dedup_key":   notice.HookID,
+	}
+
+	switch strings.ToLower(alert.Status) {
+	case "firing":
+		event["event_action"] = "trigger"
+	case "resolved":
+		event["event_action"] = "resolve"
+		return event, nil
+	default:
+		return nil, fmt.Errorf("unsupport alert status - %s", alert.Status)
+	}
+
+	summary := alert.Annotations[labelSummary]
+	if summary == "" {
+		summary = strings.SplitN(strings.TrimSpace(notice.Message), "\n", 2)[0]
+	}
+
+	details := map[string]string{}
+	for name, label := range common.CONF.Webhook.LabelMapper {
+		details[name] = alert.Labels[label]
+	}
+
+	source := alert.Labels[labelInstance]
+	if source == "" {
+		source = "webhook-go"
+	}
+
+	event["payload"] = map[string]interface{}{
+		"summary":        truncate(summary, 1024),
+		"source":         source,
+		"severity":       o.severity(target, alert.Labels[labelLevel]),
+		"timestamp":      alert.StartsAt.Format(time.RFC3339),
+		"component":      alert.Labels[labelJob],
+		"class":          alert.Labels[labelAlertname],
+		"custom_details": details,
+	}
+	return event, nil
+}
+
+// severity level -> pagerduty severity, default "error"
+func (o *pagerDutyNotifier) severity(target common.WebhookTarget, level string) string {
+	if s, ok := target.PagerDuty.Severity[level]; ok {
+		return s
+	}
+	if pagerDutySeverities[level] {
+		return level
+	}
+	return "error"
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestPagerDutyNotifier(t *testing.T) {
+	server := newTestServer(t)
+	defaultAPI := pagerDutyAPI
+	pagerDutyAPI = server.URL + "/v2/enqueue"
+	defer func() { pagerDutyAPI = defaultAPI }()
+
+	tests := []struct {
+		name         string
+		status       string
+		severity     map[string]string
+		wantAction   string
+		wantSeverity string
+	}{
+		{name: "trigger", status: "firing", wantAction: "trigger", wantSeverity: "critical"},
+		{name: "mapped severity", status: "firing", severity: map[string]string{"critical": "error"}, wantAction: "trigger", wantSeverity: "error"},
+		{name: "resolve", status: "resolved", wantAction: "resolve"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := common.WebhookTarget{
+				Type:      "pagerduty",
+				PagerDuty: common.PagerDutyTarget{RoutingKey: "routing-key", Severity: tt.severity},
+			}
+			if _, err := (&pagerDutyNotifier{}).Notify(target, testNotice(tt.status)); err != nil {
+				t.Fatal(err)
+			}
+
+			requests := server.received()
+			if len(requests) != i+1 {
+				t.Fatalf("received %d requests, want %d", len(requests), i+1)
+			}
+			req := requests[i]
+			if req.Path != "/v2/enqueue" || req.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("request = %s %s", req.Path, req.Header.Get("Content-Type"))
+			}
+
+			event := decodeBody(t, req)
+			if event["routing_key"] != "routing-key" || event["dedup_key"] != "hook-1" || event["event_action"] != tt.wantAction {
+				t.Errorf("event = %v", event)
+			}
+			payload, ok := event["payload"].(map[string]interface{})
+			if tt.wantAction == "resolve" {
+				if ok {
+					t.Errorf("resolve event has payload %v", payload)
+				}
+				return
+			}
+			if payload["summary"] != "mysql is down" || payload["source"] != "db1:3306" || payload["severity"] != tt.wantSeverity {
+				t.Errorf("payload = %v", payload)
+			}
+			if payload["class"] != "MySQLDown" || payload["component"] != "mysql" || payload["timestamp"] != "2021-05-01T10:00:00Z" {
+				t.Errorf("payload = %v", payload)
+			}
+		})
+	}
+}
+
+func TestPagerDutyNotifierTargetAPI(t *testing.T) {
+	server := newTestServer(t)
+	target := common.WebhookTarget{
+		Type:      "pagerduty",
+		API:       server.URL + "/custom",
+		PagerDuty: common.PagerDutyTarget{RoutingKey: "routing-key"},
+	}
+	if _, err := (&pagerDutyNotifier{}).Notify(target, testNotice("firing")); err != nil {
+		t.Fatal(err)
+	}
+	if req := server.only(t); req.Path != "/custom" {
+		t.Errorf("path = %s, want /custom", req.Path)
+	}
+}
+
+func TestPagerDutyNotifierInvalid(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name   string
+		key    string
+		status string
+	}{
+		{name: "no routing key", status: "firing"},
+		{name: "unknown status", key: "routing-key", status: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := common.WebhookTarget{API: server.URL, PagerDuty: common.PagerDutyTarget{RoutingKey: tt.key}}
+			if _, err := (&pagerDutyNotifier{}).Notify(target, testNotice(tt.status)); err == nil {
+				t.Error("error is nil")
+			}
+		})
+	}
+	if requests := server.received(); len(requests) != 0 {
+		t.Errorf("received %d requests, want 0", len(requests))
+	}
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+// slackNotifier slack incoming webhook with block kit payload
+type slackNotifier struct{}
+
+var slackDefaultColors = map[string]string{
+	"critical": "#E01E5A",
+	"warning":  "#ECB22E",
+	"resolved": "#2EB67D",
+	"default":  "#439FE0",
+}
+
+func init() {
+	RegisterNotifier("slack", &slackNotifier{})
+}
+
+// Notify post block kit message to slack webhook api
+func (o *slackNotifier) Notify(target common.WebhookTarget, notice *Notice) (Response, error) {
+	b, err := json.Marshal(o.payload(target, notice))
+	if err != nil {
+		return Response{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target.API, bytes.NewReader(b))
+	if err != nil {
+		return Response{}, stripURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(target, notice, req)
+}
+
+// payload build slack message, colour bar attachment has the blocks
+func (o *slackNotifier) payload(target common.WebhookTarget, notice *Notice) map[string]interface{} {
+	alert := notice.Alert
+	summary := alert.Annotations[labelSummary]
+	message := strings.TrimSpace(notice.Message)
+	location := common.GetLocation()
+
+	var title, color string
+	var blocks []interface{}
+	switch strings.ToLower(alert.Status) {
+	case "resolved":
+		title = fmt.Sprintf("[RESOLVED] %s", summary)
+		color = o.color(target, "resolved")
+		blocks = []interface{}{
+			slackHeader(title),
+			slackSection(message),
+			slackContext(fmt.Sprintf("Start: %s  |  End: %s",
+				alert.StartsAt.In(location).Format("01/02 15:04:05 MST"),
+				alert.EndsAt.In(location).Format("01/02 15:04:05 MST"))),
+		}
+	default:
+		title = fmt.Sprintf("[FIRING] %s", summary)
+		color = o.color(target, alert.Labels[labelLevel])
+		blocks = []interface{}{
+			slackHeader(title),
+			slackSection(message),
+		}
+		if fields := slackFields(notice); len(fields) > 0 {
+			blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+		}
+		blocks = append(blocks, slackContext(fmt.Sprintf("Start: %s",
+			alert.StartsAt.In(location).Format("01/02 15:04:05 MST"))))
+	}
+
+	payload := map[string]interface{}{
+		"text": title,
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"color":  color,
+				"blocks": blocks,
+			},
+		},
+	}
+	if target.Slack.Channel != "" {
+		payload["channel"] = target.Slack.Channel
+	}
+	if target.Slack.Username != "" {
+		payload["username"] = target.Slack.Username
+	}
+	if target.Slack.IconEmoji != "" {
+		payload["icon_emoji"] = target.Slack.IconEmoji
+	}
+	return payload
+}
+
+// color target colour for key, then default colour
+func (o *slackNotifier) color(target common.WebhookTarget, key string) string {
+	if c, ok := target.Slack.Colors[key]; ok {
+		return c
+	}
+	if c, ok := slackDefaultColors[key]; ok {
+		return c
+	}
+	if c, ok := target.Slack.Colors["default"]; ok {
+		return c
+	}
+	return slackDefaultColors["default"]
+}
+
+// slackFields mapped labels as section fields, slack allows 10 fields
+func slackFields(notice *Notice) []interface{} {
+	var fields []interface{}
+	for _, pair := range mappedPairs(common.CONF.Webhook.LabelMapper, notice.Alert.Labels) {
+		if len(fields) == 10 {
+			break
+		}
+		fields = append(fields, map[string]interface{}{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("*%s*\n%s", pair.Name, pair.Value),
+		})
+	}
+	return fields
+}
+
+func slackHeader(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "header",
+		"text": map[string]interface{}{"type": "plain_text", "text": truncate(text, 150)},
+	}
+}
+
+func slackSection(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "section",
+		"text": map[string]interface{}{"type": "mrkdwn", "text": truncate(text, 3000)},
+	}
+}
+
+func slackContext(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "context",
+		"elements": []interface{}{map[string]interface{}{"type": "mrkdwn", "text": text}},
+	}
+}
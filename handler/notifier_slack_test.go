@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		slack     common.SlackTarget
+		wantText  string
+		wantColor string
+		wantTypes []string
+	}{
+		{
+			name:      "firing with fields",
+			status:    "firing",
+			slack:     common.SlackTarget{Channel: "#alert", Username: "webhook", IconEmoji: ":fire:"},
+			wantText:  "[FIRING] mysql is down",
+			wantColor: slackDefaultColors["critical"],
+			wantTypes: []string{"header", "section", "section", "context"},
+		},
+		{
+			name:      "resolved with target colour",
+			status:    "resolved",
+			slack:     common.SlackTarget{Colors: map[string]string{"resolved": "#000000"}},
+			wantText:  "[RESOLVED] mysql is down",
+			wantColor: "#000000",
+			wantTypes: []string{"header", "section", "context"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			target := common.WebhookTarget{Type: "slack", API: server.URL + "/services/T0/B0/X0", Slack: tt.slack}
+			if _, err := (&slackNotifier{}).Notify(target, testNotice(tt.status)); err != nil {
+				t.Fatal(err)
+			}
+
+			req := server.only(t)
+			if req.Method != http.MethodPost || req.Path != "/services/T0/B0/X0" {
+				t.Errorf("request = %s %s", req.Method, req.Path)
+			}
+			if got := req.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("content type = %s, want application/json", got)
+			}
+
+			body := decodeBody(t, req)
+			if body["text"] != tt.wantText {
+				t.Errorf("text = %v, want %s", body["text"], tt.wantText)
+			}
+			for key, want := range map[string]string{"channel": tt.slack.Channel, "username": tt.slack.Username, "icon_emoji": tt.slack.IconEmoji} {
+				got, ok := body[key]
+				if want == "" && ok || want != "" && got != want {
+					t.Errorf("%s = %v, want %q", key, got, want)
+				}
+			}
+
+			attachment := body["attachments"].([]interface{})[0].(map[string]interface{})
+			if attachment["color"] != tt.wantColor {
+				t.Errorf("color = %v, want %s", attachment["color"], tt.wantColor)
+			}
+			var types []string
+			for _, block := range attachment["blocks"].([]interface{}) {
+				types = append(types, block.(map[string]interface{})["type"].(string))
+			}
+			if strings.Join(types, ",") != strings.Join(tt.wantTypes, ",") {
+				t.Errorf("blocks = %v, want %v", types, tt.wantTypes)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-gywn/webhook-go/common"
+	t "github.com/prometheus/alertmanager/template"
+)
+
+// teamsNotifier microsoft teams incoming webhook with adaptive card
+type teamsNotifier struct{}
+
+var teamsThemeColors = map[string]string{
+	"critical": "E01E5A",
+	"warning":  "ECB22E",
+	"resolved": "2EB67D",
+}
+
+func init() {
+	RegisterNotifier("teams", &teamsNotifier{})
+}
+
+// Notify post card to teams webhook api
+func (o *teamsNotifier) Notify(target common.WebhookTarget, notice *Notice) (Response, error) {
+	var payload map[string]interface{}
+	if target.Teams.MessageCard {
+		payload = o.messageCard(notice)
+	} else {
+		payload = o.adaptiveCard(notice)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target.API, bytes.NewReader(b))
+	if err != nil {
+		return Response{}, stripURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(target, notice, req)
+}
+
+// adaptiveCard title from summary, facts from mapped labels and annotations
+func (o *teamsNotifier) adaptiveCard(notice *Notice) map[string]interface{} {
+	var facts []interface{}
+	for _, pair := range teamsFacts(notice) {
+		facts = append(facts, map[string]interface{}{"title": pair.Name, "value": pair.Value})
+	}
+
+	titleColor := "Attention"
+	if strings.ToLower(notice.Alert.Status) == "resolved" {
+		titleColor = "Good"
+	}
+
+	body := []interface{}{
+		map[string]interface{}{
+			"type":   "TextBlock",
+			"text":   teamsTitle(notice),
+			"size":   "Large",
+			"weight": "Bolder",
+			"color":  titleColor,
+			"wrap":   true,
+		},
+		map[string]interface{}{
+			"type": "TextBlock",
+			"text": strings.TrimSpace(notice.Message),
+			"wrap": true,
+		},
+		map[string]interface{}{
+			"type":  "FactSet",
+			"facts": facts,
+		},
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if notice.Alert.GeneratorURL != "" {
+		card["actions"] = []interface{}{
+			map[string]interface{}{
+				"type":  "Action.OpenUrl",
+				"title": "Open alert",
+				"url":   notice.Alert.GeneratorURL,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     card,
+			},
+		},
+	}
+}
+
+// messageCard legacy connector card for old teams webhook
+func (o *teamsNotifier) messageCard(notice *Notice) map[string]interface{} {
+	var facts []interface{}
+	for _, pair := range teamsFacts(notice) {
+		facts = append(facts, map[string]interface{}{"name": pair.Name, "value": pair.Value})
+	}
+
+	color, ok := teamsThemeColors[strings.ToLower(notice.Alert.Status)]
+	if !ok {
+		color = teamsThemeColors[notice.Alert.Labels[labelLevel]]
+	}
+
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "http://schema.org/extensions",
+		"summary":    teamsTitle(notice),
+		"title":      teamsTitle(notice),
+		"themeColor": color,
+		"text":       strings.TrimSpace(notice.Message),
+		"sections":   []interface{}{map[string]interface{}{"facts": facts}},
+	}
+	if notice.Alert.GeneratorURL != "" {
+		card["potentialAction"] = []interface{}{
+			map[string]interface{}{
+				"@type":   "OpenUri",
+				"name":    "Open alert",
+				"targets": []interface{}{map[string]interface{}{"os": "default", "uri": notice.Alert.GeneratorURL}},
+			},
+		}
+	}
+	return card
+}
+
+func teamsTitle(notice *Notice) string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(notice.Alert.Status), notice.Alert.Annotations[labelSummary])
+}
+
+func teamsFacts(notice *Notice) t.Pairs {
+	pairs := mappedPairs(common.CONF.Webhook.LabelMapper, notice.Alert.Labels)
+	return append(pairs, mappedPairs(common.CONF.Webhook.AnnotationMapper, notice.Alert.Annotations)...)
+}
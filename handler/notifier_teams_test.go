@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestTeamsNotifierAdaptiveCard(t *testing.T) {
+	server := newTestServer(t)
+	target := common.WebhookTarget{Type: "teams", API: server.URL + "/webhookb2/abc"}
+	if _, err := (&teamsNotifier{}).Notify(target, testNotice("firing")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := server.only(t)
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %s %s", req.Method, req.Header.Get("Content-Type"))
+	}
+
+	body := decodeBody(t, req)
+	if body["type"] != "message" {
+		t.Errorf("type = %v, want message", body["type"])
+	}
+	attachment := body["attachments"].([]interface{})[0].(map[string]interface{})
+	if attachment["contentType"] != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("contentType = %v", attachment["contentType"])
+	}
+	card := attachment["content"].(map[string]interface{})
+	if card["type"] != "AdaptiveCard" {
+		t.Errorf("card type = %v, want AdaptiveCard", card["type"])
+	}
+
+	blocks := card["body"].([]interface{})
+	title := blocks[0].(map[string]interface{})
+	if title["text"] != "[FIRING] mysql is down" || title["color"] != "Attention" {
+		t.Errorf("title = %v", title)
+	}
+	facts := blocks[2].(map[string]interface{})["facts"].([]interface{})
+	if want := len(common.CONF.Webhook.LabelMapper) + len(common.CONF.Webhook.AnnotationMapper); len(facts) != want {
+		t.Errorf("facts = %d, want %d", len(facts), want)
+	}
+	action := card["actions"].([]interface{})[0].(map[string]interface{})
+	if action["url"] != "http://prometheus:9090/graph" {
+		t.Errorf("action url = %v", action["url"])
+	}
+}
+
+func TestTeamsNotifierMessageCard(t *testing.T) {
+	server := newTestServer(t)
+	target := common.WebhookTarget{Type: "teams", API: server.URL, Teams: common.TeamsTarget{MessageCard: true}}
+	if _, err := (&teamsNotifier{}).Notify(target, testNotice("resolved")); err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeBody(t, server.only(t))
+	if body["@type"] != "MessageCard" {
+		t.Errorf("@type = %v, want MessageCard", body["@type"])
+	}
+	if body["title"] != "[RESOLVED] mysql is down" {
+		t.Errorf("title = %v", body["title"])
+	}
+	if body["themeColor"] != teamsThemeColors["resolved"] {
+		t.Errorf("themeColor = %v, want %s", body["themeColor"], teamsThemeColors["resolved"])
+	}
+	if body["text"] != "mysql is down\ndb1 & db2 <critical>" {
+		t.Errorf("text = %q", body["text"])
+	}
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+// telegramNotifier telegram bot api sendMessage
+type telegramNotifier struct{}
+
+var telegramAPI = "https://api.telegram.org"
+var telegramMaxLength = 4096
+
+var telegramEscapers = map[string]*strings.Replacer{
+	"markdown": strings.NewReplacer(
+		"_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[",
+	),
+	"markdownv2": strings.NewReplacer(
+		"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+		"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=",
+		"|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+	),
+	"html": strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", ">", "&gt;",
+	),
+}
+
+func init() {
+	RegisterNotifier("telegram", &telegramNotifier{})
+}
+
+// Notify send message to every chat, long message is split,
+// message is sent as rendered, values are escaped in template with telegramEscape
+func (o *telegramNotifier) Notify(target common.WebhookTarget, notice *Notice) (Response, error) {
+	opt := target.Telegram
+	if opt.Token == "" || len(opt.ChatIDs) == 0 {
+		return Response{}, fmt.Errorf("telegram token and chat ids must be set")
+	}
+
+	parseMode := strings.ToLower(opt.ParseMode)
+	if _, ok := telegramEscapers[parseMode]; !ok && parseMode != "" {
+		return Response{}, fmt.Errorf("unsupport telegram parse mode - %s", opt.ParseMode)
+	}
+
+	api := target.API
+	if api == "" {
+		api = telegramAPI
+	}
+	api = strings.TrimRight(api, "/") + "/bot" + opt.Token + "/sendMessage"
+
+	// failed chats only at retry, from the failed chunk
+	recipients := opt.ChatIDs
+	if len(notice.Recipients) > 0 {
+		recipients = notice.Recipients
+	}
+
+	var errs, failedChats []string
+	var response, failed Response
+	chunks := splitMessage(strings.TrimSpace(notice.Message), telegramMaxLength)
+	for _, recipient := range recipients {
+		chatID, from := telegramRecipient(recipient)
+		for i := from; i < len(chunks); i++ {
+			resp, err := o.send(target, notice, api, chatID, chunks[i])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("chat %s - %s", chatID, err.Error()))
+				if i > 0 {
+					chatID = fmt.Sprintf("%s:%d", chatID, i)
+				}
+				failedChats = append(failedChats, chatID)
+				failed = resp
+				break
+			}
+			response = resp
+		}
+	}
+	if len(errs) > 0 {
+		return failed, &RecipientError{Failed: failedChats, Err: fmt.Errorf("%s", strings.Join(errs, ", "))}
+	}
+	return response, nil
+}
+
+func (o *telegramNotifier) send(target common.WebhookTarget, notice *Notice, api string, chatID string, text string) (Response, error) {
+	body := map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}
+	if target.Telegram.ParseMode != "" {
+		body["parse_mode"] = target.Telegram.ParseMode
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, api, bytes.NewReader(b))
+	if err != nil {
+		return Response{}, stripURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(target, notice, req)
+}
+
+// telegramRecipient chat id and first chunk to send, "<chat id>:<chunk>" for chat failed at chunk
+func telegramRecipient(recipient string) (string, int) {
+	if i := strings.LastIndex(recipient, ":"); i > 0 {
+		if chunk, err := strconv.Atoi(recipient[i+1:]); err == nil && chunk >= 0 {
+			return recipient[:i], chunk
+		}
+	}
+	return recipient, 0
+}
+
+// telegramEscape escape value for parse mode, "" or unknown mode is not escaped
+func telegramEscape(parseMode string, s string) string {
+	if escaper, ok := telegramEscapers[strings.ToLower(parseMode)]; ok {
+		return escaper.Replace(s)
+	}
+	return s
+}
+
+// splitMessage split message by lines so that every chunk fits in max runes,
+// a line longer than max is cut by runes
+func splitMessage(message string, max int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentLength := 0
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentLength = 0
+		}
+	}
+
+	lines := strings.SplitAfter(message, "\n")
+	for _, line := range lines {
+		lineLength := len([]rune(line))
+		if currentLength+lineLength <= max {
+			current.WriteString(line)
+			currentLength += lineLength
+			continue
+		}
+		flush()
+
+		if lineLength <= max {
+			current.WriteString(line)
+			currentLength = lineLength
+			continue
+		}
+
+		// line is too long, cut by runes
+		for _, r := range line {
+			if currentLength+1 > max {
+				flush()
+			}
+			current.WriteRune(r)
+			currentLength++
+		}
+	}
+	flush()
+	return chunks
+}
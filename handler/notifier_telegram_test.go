@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	server := newTestServer(t)
+	target := common.WebhookTarget{
+		Type:     "telegram",
+		API:      server.URL + "/",
+		Telegram: common.TelegramTarget{Token: "123:abc", ChatIDs: []string{"100", "200"}, ParseMode: "HTML"},
+	}
+	// markup is sent as rendered, values are escaped by template
+	notice := testNotice("firing")
+	notice.Vars[labelDescription] = "db1 & db2 <critical>"
+	tpl, err := parseTemplate("template", `<b>{{ .summary }}</b>`+"\n"+`{{ .description | telegramEscape "HTML" }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, notice.Vars); err != nil {
+		t.Fatal(err)
+	}
+	notice.Message = buf.String()
+	if _, err = (&telegramNotifier{}).Notify(target, notice); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := server.received()
+	if len(requests) != 2 {
+		t.Fatalf("received %d requests, want 2", len(requests))
+	}
+	for i, chatID := range []string{"100", "200"} {
+		req := requests[i]
+		if req.Method != http.MethodPost || req.Path != "/bot123:abc/sendMessage" {
+			t.Errorf("request = %s %s", req.Method, req.Path)
+		}
+		body := decodeBody(t, req)
+		if body["chat_id"] != chatID || body["parse_mode"] != "HTML" || body["disable_web_page_preview"] != true {
+			t.Errorf("body = %v", body)
+		}
+		if body["text"] != "<b>mysql is down</b>\ndb1 &amp; db2 &lt;critical&gt;" {
+			t.Errorf("text = %q", body["text"])
+		}
+	}
+}
+
+func TestTelegramNotifierFailedChats(t *testing.T) {
+	server := newTestServer(t)
+	server.reply = func(r testRequest) int {
+		if strings.Contains(string(r.Body), `"chat_id":"200"`) {
+			return http.StatusBadRequest
+		}
+		return http.StatusOK
+	}
+	target := common.WebhookTarget{
+		Type:     "telegram",
+		API:      server.URL,
+		Telegram: common.TelegramTarget{Token: "123:abc", ChatIDs: []string{"100", "200", "300"}},
+	}
+
+	notice := testNotice("firing")
+	_, err := (&telegramNotifier{}).Notify(target, notice)
+	if err == nil {
+		t.Fatal("error is nil with failed chat")
+	}
+	if got := failedRecipients(err); got != "200" {
+		t.Errorf("failed recipients = %q, want 200", got)
+	}
+	if strings.Contains(err.Error(), "123:abc") {
+		t.Errorf("error has bot token - %s", err)
+	}
+
+	// retry sends to failed chats only
+	server.reply = nil
+	notice.Recipients = splitRecipients(failedRecipients(err))
+	if _, err = (&telegramNotifier{}).Notify(target, notice); err != nil {
+		t.Fatal(err)
+	}
+	requests := server.received()
+	if len(requests) != 4 {
+		t.Fatalf("received %d requests, want 4", len(requests))
+	}
+	if body := decodeBody(t, requests[3]); body["chat_id"] != "200" {
+		t.Errorf("retry chat = %v, want 200", body["chat_id"])
+	}
+}
+
+func TestTelegramNotifierFailedChunk(t *testing.T) {
+	server := newTestServer(t)
+	defaultMaxLength := telegramMaxLength
+	telegramMaxLength = 6
+	defer func() { telegramMaxLength = defaultMaxLength }()
+
+	server.reply = func(r testRequest) int {
+		if strings.Contains(string(r.Body), `"chat_id":"200"`) && strings.Contains(string(r.Body), "second") {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusOK
+	}
+	target := common.WebhookTarget{
+		Type:     "telegram",
+		API:      server.URL,
+		Telegram: common.TelegramTarget{Token: "123:abc", ChatIDs: []string{"100", "200"}},
+	}
+
+	notice := testNotice("firing")
+	notice.Message = "first\nsecond\nthird"
+	_, err := (&telegramNotifier{}).Notify(target, notice)
+	if got := failedRecipients(err); got != "200:1" {
+		t.Fatalf("failed recipients = %q, want 200:1", got)
+	}
+
+	// retry sends from the failed chunk only
+	server.reply = nil
+	notice.Recipients = splitRecipients(failedRecipients(err))
+	if _, err = (&telegramNotifier{}).Notify(target, notice); err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	for _, req := range server.received() {
+		body := decodeBody(t, req)
+		sent = append(sent, body["chat_id"].(string)+" "+strings.TrimSpace(body["text"].(string)))
+	}
+	want := []string{"100 first", "100 second", "100 third", "200 first", "200 second", "200 second", "200 third"}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent = %q, want %q", sent, want)
+	}
+}
+
+func TestTelegramRecipient(t *testing.T) {
+	tests := []struct {
+		recipient string
+		chatID    string
+		chunk     int
+	}{
+		{"100", "100", 0},
+		{"-1001234", "-1001234", 0},
+		{"@channel", "@channel", 0},
+		{"200:3", "200", 3},
+		{"-1001234:1", "-1001234", 1},
+		{"200:x", "200:x", 0},
+		{":1", ":1", 0},
+	}
+	for _, tt := range tests {
+		chatID, chunk := telegramRecipient(tt.recipient)
+		if chatID != tt.chatID || chunk != tt.chunk {
+			t.Errorf("telegramRecipient(%q) = %q, %d, want %q, %d", tt.recipient, chatID, chunk, tt.chatID, tt.chunk)
+		}
+	}
+}
+
+func TestTelegramEscape(t *testing.T) {
+	tests := []struct {
+		parseMode string
+		s         string
+		want      string
+	}{
+		{"HTML", "db1 & <db2>", "db1 &amp; &lt;db2&gt;"},
+		{"Markdown", "disk_usage *high*", `disk\_usage \*high\*`},
+		{"MarkdownV2", "db1.example (90%)!", `db1\.example \(90%\)\!`},
+		{"", "<b>_x_</b>", "<b>_x_</b>"},
+	}
+	for _, tt := range tests {
+		if got := telegramEscape(tt.parseMode, tt.s); got != tt.want {
+			t.Errorf("telegramEscape(%q, %q) = %q, want %q", tt.parseMode, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTelegramNotifierSplit(t *testing.T) {
+	server := newTestServer(t)
+	defaultMaxLength := telegramMaxLength
+	telegramMaxLength = 20
+	defer func() { telegramMaxLength = defaultMaxLength }()
+
+	target := common.WebhookTarget{
+		Type:     "telegram",
+		API:      server.URL,
+		Telegram: common.TelegramTarget{Token: "123:abc", ChatIDs: []string{"100"}},
+	}
+	notice := testNotice("firing")
+	notice.Message = "first line is long\nsecond line\nthird"
+	if _, err := (&telegramNotifier{}).Notify(target, notice); err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+	for _, req := range server.received() {
+		texts = append(texts, decodeBody(t, req)["text"].(string))
+	}
+	want := []string{"first line is long\n", "second line\nthird"}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+}
+
+func TestTelegramNotifierInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		telegram common.TelegramTarget
+	}{
+		{name: "no token", telegram: common.TelegramTarget{ChatIDs: []string{"100"}}},
+		{name: "no chat", telegram: common.TelegramTarget{Token: "123:abc"}},
+		{name: "unknown parse mode", telegram: common.TelegramTarget{Token: "123:abc", ChatIDs: []string{"100"}, ParseMode: "rst"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := common.WebhookTarget{Type: "telegram", Telegram: tt.telegram}
+			if _, err := (&telegramNotifier{}).Notify(target, testNotice("firing")); err == nil {
+				t.Error("error is nil")
+			}
+		})
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		max     int
+		want    []string
+	}{
+		{name: "fits", message: "a\nb\nc", max: 10, want: []string{"a\nb\nc"}},
+		{name: "by lines", message: "aaa\nbbb\nccc", max: 8, want: []string{"aaa\nbbb\n", "ccc"}},
+		{name: "long line by runes", message: "abcdefgh\nij", max: 3, want: []string{"abc", "def", "gh\n", "ij"}},
+		{name: "multibyte runes", message: "가나다라", max: 2, want: []string{"가나", "다라"}},
+		{name: "markup is not escaped", message: "<b>a.b</b>", max: 10, want: []string{"<b>a.b</b>"}},
+		{name: "line cut by runes then next line", message: "abcd\nef", max: 4, want: []string{"abcd", "\nef"}},
+		{name: "empty", message: "", max: 10, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.message, tt.max)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	t "github.com/prometheus/alertmanager/template"
+)
+
+// testRequest request received by test server
+type testRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// testServer record requests, respond with code of reply, 200 if nil
+type testServer struct {
+	*httptest.Server
+	mtx      sync.Mutex
+	requests []testRequest
+	reply    func(r testRequest) int
+}
+
+func newTestServer(tb testing.TB) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req := testRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header, Body: body}
+
+		s.mtx.Lock()
+		s.requests = append(s.requests, req)
+		reply := s.reply
+		s.mtx.Unlock()
+
+		code := http.StatusOK
+		if reply != nil {
+			code = reply(req)
+		}
+		w.WriteHeader(code)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// received requests so far
+func (s *testServer) received() []testRequest {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]testRequest{}, s.requests...)
+}
+
+// only one request received
+func (s *testServer) only(tb testing.TB) testRequest {
+	tb.Helper()
+	requests := s.received()
+	if len(requests) != 1 {
+		tb.Fatalf("received %d requests, want 1", len(requests))
+	}
+	return requests[0]
+}
+
+// decodeBody json body as map
+func decodeBody(tb testing.TB, req testRequest) map[string]interface{} {
+	tb.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		tb.Fatalf("invalid json body %s - %s", string(req.Body), err)
+	}
+	return body
+}
+
+func testAlert(status string) t.Alert {
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	alert := t.Alert{
+		Status: status,
+		Labels: t.KV{
+			labelAlertname: "MySQLDown",
+			labelInstance:  "db1:3306",
+			labelLevel:     "critical",
+			labelJob:       "mysql",
+		},
+		Annotations: t.KV{
+			labelSummary:     "mysql is down",
+			labelDescription: "db1 does not respond",
+		},
+		StartsAt:     start,
+		GeneratorURL: "http://prometheus:9090/graph",
+		Fingerprint:  "0123456789abcdef",
+	}
+	if status == "resolved" {
+		alert.EndsAt = start.Add(10 * time.Minute)
+	}
+	return alert
+}
+
+func testNotice(status string) *Notice {
+	alert := testAlert(status)
+	return &Notice{
+		HookID:  "hook-1",
+		Alert:   alert,
+		Vars:    alertVars(alert),
+		Message: "mysql is down\ndb1 & db2 <critical>\n",
+	}
+}
+
+func TestGetNotifier(t *testing.T) {
+	tests := []struct {
+		targetType string
+		want       Notifier
+		wantErr    bool
+	}{
+		{targetType: "", want: notifiers["http"]},
+		{targetType: "HTTP", want: notifiers["http"]},
+		{targetType: "sms", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := GetNotifier(tt.targetType)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetNotifier(%q) error = %v, wantErr %v", tt.targetType, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("GetNotifier(%q) = %T, want %T", tt.targetType, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello world", 8, "hello..."},
+		{"가나다라마", 4, "가..."},
+		{"hello", 3, "..."},
+		{"hello", 2, "he"},
+		{"hello", 0, ""},
+		{"hello", -1, ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	long := errors.New(strings.Repeat("x", maxErrorLength*70))
+	if got := errorText(long); len([]rune(got)) != maxErrorLength || !strings.HasSuffix(got, "...") {
+		t.Errorf("errorText() length = %d, want %d with ...", len([]rune(got)), maxErrorLength)
+	}
+	if got := errorText(errors.New("response code 500")); got != "response code 500" {
+		t.Errorf("errorText() = %q, want response code 500", got)
+	}
+}
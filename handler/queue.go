@@ -0,0 +1,388 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+	"github.com/go-gywn/webhook-go/model"
+	t "github.com/prometheus/alertmanager/template"
+)
+
+// hookTask alert to process, or whole notification in group mode
+// queueID is hook_queue row of durable queue
+type hookTask struct {
+	alert   t.Alert
+	data    *groupData
+	queueID int
+}
+
+// hookID hook id of alert or group
+func (o *hookTask) hookID() string {
+	if o.data != nil {
+		return getGroupHookID(*o.data)
+	}
+	return getHookID(o.alert)
+}
+
+// reqJSON request json to save, alert or alertmanager notification
+func (o *hookTask) reqJSON() string {
+	var b []byte
+	if o.data != nil {
+		b, _ = json.Marshal(o.data)
+	} else {
+		b, _ = json.Marshal(o.alert)
+	}
+	return string(b)
+}
+
+// decodeTask task from saved request json, notification has alerts
+func decodeTask(reqJSON string) (task hookTask, err error) {
+	var probe struct {
+		Alerts []json.RawMessage `json:"alerts"`
+	}
+	if err = json.Unmarshal([]byte(reqJSON), &probe); err != nil {
+		return
+	}
+	if probe.Alerts != nil {
+		task.data = &groupData{}
+		err = json.Unmarshal([]byte(reqJSON), task.data)
+		return
+	}
+	err = json.Unmarshal([]byte(reqJSON), &task.alert)
+	return
+}
+
+// hookQueues one queue per worker, alerts of same hook id go to same queue in order
+var hookQueues []chan hookTask
+var hookWorkers = &sync.WaitGroup{}
+
+// queueClosed no more alert after shutdown, quitWorkers stop workers at shutdown timeout
+var queueMtx = &sync.RWMutex{}
+
+// enqueueMtx producers check room and push together
+var enqueueMtx = &sync.Mutex{}
+var queueClosed bool
+var quitWorkers = make(chan struct{})
+
+// rejectedAlerts alerts rejected because queue is full
+var rejectedAlerts uint64
+
+// errQueueFull enqueue timeout
+var errQueueFull = fmt.Errorf("hook queue is full")
+var errQueueClosed = fmt.Errorf("hook queue is closed")
+
+// QueueStats queue depth and rejected alerts
+type QueueStats struct {
+	Depth    int    `json:"depth"`
+	Size     int    `json:"size"`
+	Depths   []int  `json:"depths"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// newHookQueues split queue size to workers
+func newHookQueues() []chan hookTask {
+	workers := common.CONF.Webhook.Workers
+	if workers <= 0 {
+		workers = 5
+	}
+	size := common.CONF.Webhook.QueueSize / workers
+	if size <= 0 {
+		size = 1
+	}
+
+	hookQueues = make([]chan hookTask, workers)
+	for i := range hookQueues {
+		hookQueues[i] = make(chan hookTask, size)
+	}
+	logger.Info("hook queue > ", workers, " workers, ", size, " per worker")
+	return hookQueues
+}
+
+// enqueue put alert to the queue of its hook id, errQueueFull after enqueue timeout
+func enqueue(alert t.Alert) error {
+	return enqueueAll([]t.Alert{alert})
+}
+
+// enqueueAll put alerts of a request together, nothing is queued if there is no room for all
+func enqueueAll(alerts []t.Alert) error {
+	var tasks []hookTask
+	for _, alert := range alerts {
+		tasks = append(tasks, hookTask{alert: alert})
+	}
+	return enqueueTasks(tasks)
+}
+
+// enqueueGroup put whole notification as one task
+func enqueueGroup(data groupData) error {
+	return enqueueTasks([]hookTask{{data: &data}})
+}
+
+// enqueueTasks with durable queue, tasks are saved to hook_queue table in a transaction
+func enqueueTasks(tasks []hookTask) error {
+	if common.CONF.Webhook.DurableQueue.Enabled {
+		if err := model.InsertHookQueues(toHookQueues(tasks)); err != nil {
+			atomic.AddUint64(&rejectedAlerts, uint64(len(tasks)))
+			return err
+		}
+		return nil
+	}
+
+	if err := pushAll(tasks); err != nil {
+		atomic.AddUint64(&rejectedAlerts, uint64(len(tasks)))
+		return err
+	}
+	return nil
+}
+
+func push(task hookTask) error {
+	return pushAll([]hookTask{task})
+}
+
+// pushAll wait until every queue has room for its tasks, then push all in order,
+// so a rejected request leaves nothing queued and is not processed twice at resend.
+// one deadline is for the whole request, tasks more than a queue size can never fit and are rejected at once.
+func pushAll(tasks []hookTask) error {
+	need := map[int]int{}
+	for _, task := range tasks {
+		need[partition(task.hookID())]++
+	}
+	for p, n := range need {
+		if n > cap(hookQueues[p]) {
+			logger.Warn("hook queue > ", n, " alerts for queue of size ", cap(hookQueues[p]), ", reject request")
+			return errQueueFull
+		}
+	}
+
+	deadline := time.Now().Add(enqueueTimeout())
+	for {
+		pushed, err := tryPushAll(tasks, need)
+		if pushed || err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return errQueueFull
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// tryPushAll push all tasks if every queue has room, workers only take out so the room is kept
+func tryPushAll(tasks []hookTask, need map[int]int) (bool, error) {
+	queueMtx.RLock()
+	defer queueMtx.RUnlock()
+	if queueClosed {
+		return false, errQueueClosed
+	}
+
+	enqueueMtx.Lock()
+	defer enqueueMtx.Unlock()
+	for p, n := range need {
+		if cap(hookQueues[p])-len(hookQueues[p]) < n {
+			return false, nil
+		}
+	}
+	for _, task := range tasks {
+		hookQueues[partition(task.hookID())] <- task
+	}
+	return true, nil
+}
+
+// stopHookQueues close queues and wait workers to send all alerts,
+// after ctx is done, workers stop and unsent alerts are saved for next start
+func stopHookQueues(ctx context.Context) error {
+	queueMtx.Lock()
+	queueClosed = true
+	for _, queue := range hookQueues {
+		close(queue)
+	}
+	queueMtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		hookWorkers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("hook queue > ", "all alerts are processed")
+		return nil
+	case <-ctx.Done():
+	}
+
+	// stop workers after current alert
+	close(quitWorkers)
+	<-done
+
+	// durable queue rows are released, others are saved
+	var tasks []hookTask
+	var claimed []int
+	for _, queue := range hookQueues {
+		for task := range queue {
+			if task.queueID > 0 {
+				claimed = append(claimed, task.queueID)
+			} else {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	logger.Info("hook queue > ", "save ", len(tasks), " unsent alerts, release ", len(claimed), " claimed alerts")
+	if err := model.ReleaseHookQueues(claimed); err != nil {
+		return err
+	}
+	return model.InsertHookQueues(toHookQueues(tasks))
+}
+
+// startQueuePoller claim durable queue rows as many as free queue slots, in arrival order
+func startQueuePoller() {
+	conf := common.CONF.Webhook.DurableQueue
+	interval := time.Duration(conf.PollMs) * time.Millisecond
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	lease := time.Duration(conf.LeaseSec) * time.Second
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+
+	go func() {
+		for {
+			queueMtx.RLock()
+			closed := queueClosed
+			queueMtx.RUnlock()
+			if closed {
+				return
+			}
+
+			stats := getQueueStats()
+			free := stats.Size - stats.Depth
+			if free > 100 {
+				free = 100
+			}
+
+			var queues []model.HookQueue
+			if free > 0 {
+				var err error
+				if queues, err = model.ClaimHookQueues(instanceID, free, lease); err != nil {
+					logger.Error("queue poller > ", err)
+				}
+			}
+
+			for i, queue := range queues {
+				task, err := decodeTask(queue.ReqJSON)
+				if err != nil {
+					logger.Error("queue poller > ", "invalid queued alert ", queue.ID, " - ", err)
+					queue.Delete()
+					continue
+				}
+				task.queueID = queue.ID
+				if err = push(task); err != nil {
+					// release the rest to claim again
+					var ids []int
+					for _, q := range queues[i:] {
+						ids = append(ids, q.ID)
+					}
+					model.ReleaseHookQueues(ids)
+					break
+				}
+			}
+
+			if len(queues) == 0 {
+				time.Sleep(interval)
+			}
+		}
+	}()
+}
+
+// restoreHookQueues enqueue alerts saved at last shutdown in background, waiting for free slots,
+// durable queue poller does it itself
+func restoreHookQueues() {
+	if common.CONF.Webhook.DurableQueue.Enabled {
+		return
+	}
+
+	go func() {
+		restored := 0
+		for {
+			queues, err := (&model.HookQueue{}).GetList(100)
+			if err != nil {
+				time.Sleep(enqueueTimeout())
+				continue
+			}
+			if len(queues) == 0 {
+				if restored > 0 {
+					logger.Info("hook queue > ", "restored ", restored, " saved alerts")
+				}
+				return
+			}
+
+			for _, queue := range queues {
+				task, err := decodeTask(queue.ReqJSON)
+				if err != nil {
+					logger.Error("hook queue > ", "invalid saved alert ", queue.ID, " - ", err)
+				} else if !restoreTask(task) {
+					logger.Info("hook queue > ", "restore stopped, queue is closed")
+					return
+				}
+				if err = queue.Delete(); err != nil {
+					time.Sleep(enqueueTimeout())
+					break
+				}
+				restored++
+			}
+		}
+	}()
+}
+
+// restoreTask push task, wait while queue is full, false if queue is closed
+func restoreTask(task hookTask) bool {
+	for {
+		err := push(task)
+		if err == nil {
+			return true
+		}
+		if err == errQueueClosed {
+			return false
+		}
+	}
+}
+
+// toHookQueues tasks to hook_queue rows
+func toHookQueues(tasks []hookTask) []model.HookQueue {
+	var queues []model.HookQueue
+	for _, task := range tasks {
+		queues = append(queues, model.HookQueue{HookID: task.hookID(), ReqJSON: task.reqJSON()})
+	}
+	return queues
+}
+
+// getQueueStats current queue stats
+func getQueueStats() QueueStats {
+	stats := QueueStats{Rejected: atomic.LoadUint64(&rejectedAlerts)}
+	for _, queue := range hookQueues {
+		stats.Depth += len(queue)
+		stats.Size += cap(queue)
+		stats.Depths = append(stats.Depths, len(queue))
+	}
+	return stats
+}
+
+// partition queue index of hook id
+func partition(hookID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(hookID))
+	return int(h.Sum32() % uint32(len(hookQueues)))
+}
+
+func enqueueTimeout() time.Duration {
+	if ms := common.CONF.Webhook.EnqueueTimeoutMs; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return time.Second
+}
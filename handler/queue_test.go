@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestDecodeTask(t *testing.T) {
+	alert := testAlert("firing")
+	group := &groupData{GroupKey: `{}:{alertname="MySQLDown"}`}
+	group.Receiver = "webhook"
+	group.Status = "firing"
+	group.Alerts = append(group.Alerts, alert, testAlert("resolved"))
+	group.GroupLabels = map[string]string{labelAlertname: "MySQLDown"}
+
+	tests := []struct {
+		name       string
+		reqJSON    string
+		wantGroup  bool
+		wantAlerts int
+		wantErr    bool
+	}{
+		{name: "alert", reqJSON: (&hookTask{alert: alert}).reqJSON()},
+		{name: "notification", reqJSON: (&hookTask{data: group}).reqJSON(), wantGroup: true, wantAlerts: 2},
+		{name: "notification without alerts", reqJSON: `{"receiver":"webhook","alerts":[]}`, wantGroup: true},
+		{name: "invalid json", reqJSON: `{"labels":`, wantErr: true},
+		{name: "invalid alerts", reqJSON: `{"alerts":[1]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := decodeTask(tt.reqJSON)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if (task.data != nil) != tt.wantGroup {
+				t.Fatalf("decodeTask() group = %v, want %v", task.data != nil, tt.wantGroup)
+			}
+			if !tt.wantGroup {
+				if task.hookID() != getHookID(alert) || task.alert.Labels[labelInstance] != "db1:3306" {
+					t.Errorf("decodeTask() alert = %v", task.alert)
+				}
+				return
+			}
+			if len(task.data.Alerts) != tt.wantAlerts {
+				t.Errorf("decodeTask() alerts = %d, want %d", len(task.data.Alerts), tt.wantAlerts)
+			}
+			if tt.wantAlerts > 0 && (task.data.GroupKey != group.GroupKey || task.hookID() != getGroupHookID(*group)) {
+				t.Errorf("decodeTask() group key = %s, hook id = %s", task.data.GroupKey, task.hookID())
+			}
+		})
+	}
+}
+
+func TestGroupHookID(t *testing.T) {
+	first := groupData{GroupKey: `{}:{alertname="MySQLDown"}`}
+	first.Receiver = "webhook"
+	first.Alerts = append(first.Alerts, testAlert("firing"))
+
+	// earliest alert left the group
+	second := first
+	later := testAlert("firing")
+	later.StartsAt = later.StartsAt.Add(time.Hour)
+	second.Alerts = append(second.Alerts[:0:0], later)
+	if getGroupHookID(first) != getGroupHookID(second) {
+		t.Error("hook id changed as alerts changed")
+	}
+
+	other := first
+	other.GroupKey = `{}:{alertname="RedisDown"}`
+	if getGroupHookID(first) == getGroupHookID(other) {
+		t.Error("hook id is same for other group key")
+	}
+
+	// no group key, receiver and group labels
+	noKey := groupData{}
+	noKey.Receiver = "webhook"
+	noKey.GroupLabels = map[string]string{labelAlertname: "MySQLDown"}
+	noKey.Alerts = first.Alerts
+	noKeyLater := noKey
+	noKeyLater.Alerts = second.Alerts
+	if getGroupHookID(noKey) != getGroupHookID(noKeyLater) {
+		t.Error("hook id without group key changed as alerts changed")
+	}
+}
+
+func TestPushAll(t *testing.T) {
+	defaultQueues, defaultTimeout := hookQueues, common.CONF.Webhook.EnqueueTimeoutMs
+	defer func() { hookQueues, common.CONF.Webhook.EnqueueTimeoutMs = defaultQueues, defaultTimeout }()
+	common.CONF.Webhook.EnqueueTimeoutMs = 50
+
+	// alerts of two queues with two slots each
+	hookQueues = []chan hookTask{make(chan hookTask), make(chan hookTask)}
+	var byQueue [2][]hookTask
+	for i := 0; len(byQueue[0]) < 3 || len(byQueue[1]) < 3; i++ {
+		alert := testAlert("firing")
+		alert.Labels[labelInstance] = fmt.Sprintf("db%d:3306", i)
+		task := hookTask{alert: alert}
+		p := partition(task.hookID())
+		byQueue[p] = append(byQueue[p], task)
+	}
+
+	tests := []struct {
+		name       string
+		queued     [2]int
+		push       [2]int
+		closed     bool
+		wantErr    error
+		wantDepths [2]int
+	}{
+		{name: "room for all", push: [2]int{2, 1}, wantDepths: [2]int{2, 1}},
+		{name: "no room in one queue", queued: [2]int{1, 0}, push: [2]int{2, 1}, wantErr: errQueueFull, wantDepths: [2]int{1, 0}},
+		{name: "other queue full", queued: [2]int{0, 2}, push: [2]int{1, 1}, wantErr: errQueueFull, wantDepths: [2]int{0, 2}},
+		{name: "more than queue size", push: [2]int{3, 1}, wantErr: errQueueFull},
+		{name: "queue closed", push: [2]int{1, 0}, closed: true, wantErr: errQueueClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hookQueues = []chan hookTask{make(chan hookTask, 2), make(chan hookTask, 2)}
+			var tasks []hookTask
+			for p := range hookQueues {
+				for _, task := range byQueue[p][:tt.queued[p]] {
+					hookQueues[p] <- task
+				}
+				tasks = append(tasks, byQueue[p][:tt.push[p]]...)
+			}
+			queueMtx.Lock()
+			queueClosed = tt.closed
+			queueMtx.Unlock()
+			defer func() { queueClosed = false }()
+
+			start := time.Now()
+			if err := pushAll(tasks); err != tt.wantErr {
+				t.Fatalf("pushAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.push[0] > 2 && time.Since(start) >= enqueueTimeout() {
+				t.Errorf("pushAll() waited %v for tasks that never fit", time.Since(start))
+			}
+			for p := range hookQueues {
+				if len(hookQueues[p]) != tt.wantDepths[p] {
+					t.Errorf("queue %d depth = %d, want %d", p, len(hookQueues[p]), tt.wantDepths[p])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+	"github.com/go-gywn/webhook-go/model"
+)
+
+// startRetrier resend pending outbox, instances sharing database claim different rows
+func startRetrier() {
+	interval := time.Duration(common.CONF.Webhook.Retry.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	lease := time.Duration(common.CONF.Webhook.Retry.LeaseSec) * time.Second
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+
+	go func() {
+		for {
+			outboxes, err := model.ClaimRetryList(instanceID, 100, lease)
+			if err != nil {
+				logger.Error("retrier > ", err)
+			}
+			for _, outbox := range outboxes {
+				retry(&outbox)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// retry resend outbox, give up after max attempts
+func retry(outbox *model.HookOutbox) {
+	outbox.Attempts++
+	err := resend(outbox)
+	if recipients := failedRecipients(err); recipients != "" {
+		outbox.Recipients = recipients
+	}
+	switch {
+	case err == nil:
+		logger.Info("retrier > ", "sent ", outbox.HookID, " to ", outbox.Target, ", attempts ", outbox.Attempts)
+		outbox.Status = model.OutboxSent
+		outbox.LastError = ""
+	case outbox.Attempts >= retryMaxAttempts():
+		logger.Error("retrier > ", "give up ", outbox.HookID, " to ", outbox.Target, " - ", err)
+		outbox.Status = model.OutboxFailed
+		outbox.LastError = errorText(err)
+		deadLetter := &model.HookDeadLetter{
+			HookID:       outbox.HookID,
+			HookDetailID: outbox.HookDetailID,
+			Target:       outbox.Target,
+			Recipients:   outbox.Recipients,
+			Reason:       truncate(fmt.Sprintf("give up after %d attempts - %s", outbox.Attempts, err.Error()), maxErrorLength),
+			ReqJSON:      outbox.ReqJSON,
+			Message:      outbox.Message,
+		}
+		deadLetter.Insert()
+	default:
+		logger.Warn("retrier > ", "retry ", outbox.HookID, " to ", outbox.Target, " - ", err)
+		nextRetryAt := time.Now().Add(retryBackoff(outbox.Attempts))
+		outbox.NextRetryAt = &nextRetryAt
+		outbox.LastError = errorText(err)
+	}
+	outbox.Update()
+}
+
+// resend rebuild notice from outbox and notify
+func resend(outbox *model.HookOutbox) error {
+	target, ok := common.CONF.Webhook.Targets[outbox.Target]
+	if !ok {
+		return fmt.Errorf("target '%s' not found", outbox.Target)
+	}
+
+	task, err := decodeTask(outbox.ReqJSON)
+	if err != nil {
+		return err
+	}
+
+	alert, vars := task.alert, alertVars(task.alert)
+	if task.data != nil {
+		alert = groupAlert(task.data.Data)
+		vars = groupVars(task.data.Data, alert)
+	}
+	notice := &Notice{
+		HookID:     outbox.HookID,
+		Alert:      alert,
+		Vars:       vars,
+		Message:    outbox.Message,
+		Recipients: splitRecipients(outbox.Recipients),
+	}
+	return deliver(outbox.HookDetailID, outbox.Attempts, outbox.Target, target, notice)
+}
+
+// retryMaxAttempts maxAttempts, 5 if not set
+func retryMaxAttempts() int {
+	if n := common.CONF.Webhook.Retry.MaxAttempts; n > 0 {
+		return n
+	}
+	return 5
+}
+
+// retryBackoff backoffSec (10 if not set) * 2^(attempts-1), up to maxBackoffSec
+func retryBackoff(attempts int) time.Duration {
+	conf := common.CONF.Webhook.Retry
+	backoff := time.Duration(conf.BackoffSec) * time.Second
+	if backoff <= 0 {
+		backoff = 10 * time.Second
+	}
+	max := time.Duration(conf.MaxBackoffSec) * time.Second
+	for i := 1; i < attempts && (max <= 0 || backoff < max); i++ {
+		backoff *= 2
+	}
+	if max > 0 && backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+// requeueDeadLetter retry the target from outbox, or route the alert again if no target
+func requeueDeadLetter(deadLetter *model.HookDeadLetter) (err error) {
+	if err = deadLetter.Get(); err != nil {
+		return err
+	}
+
+	// claim dead letter first, concurrent requeue of the same dead letter must not send it twice
+	claimed, err := deadLetter.SetStatus(model.DeadLetterNew, model.DeadLetterRequeued)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("dead letter %d is already requeued", deadLetter.ID)
+	}
+	defer func() {
+		if err != nil {
+			deadLetter.SetStatus(model.DeadLetterRequeued, model.DeadLetterNew)
+		}
+	}()
+
+	if deadLetter.Target != "" {
+		outbox := &model.HookOutbox{
+			HookID:       deadLetter.HookID,
+			HookDetailID: deadLetter.HookDetailID,
+			Target:       deadLetter.Target,
+			Recipients:   deadLetter.Recipients,
+			ReqJSON:      deadLetter.ReqJSON,
+			Message:      deadLetter.Message,
+			LastError:    "requeued dead letter",
+		}
+		return outbox.Insert(0)
+	}
+	task, err := decodeTask(deadLetter.ReqJSON)
+	if err != nil {
+		return err
+	}
+	return enqueueTasks([]hookTask{task})
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	defaultRetry := common.CONF.Webhook.Retry
+	defer func() { common.CONF.Webhook.Retry = defaultRetry }()
+
+	tests := []struct {
+		name          string
+		backoffSec    int
+		maxBackoffSec int
+		attempts      int
+		want          time.Duration
+	}{
+		{name: "first attempt", backoffSec: 10, maxBackoffSec: 600, attempts: 1, want: 10 * time.Second},
+		{name: "zero attempts", backoffSec: 10, maxBackoffSec: 600, attempts: 0, want: 10 * time.Second},
+		{name: "doubled", backoffSec: 10, maxBackoffSec: 600, attempts: 4, want: 80 * time.Second},
+		{name: "max", backoffSec: 10, maxBackoffSec: 600, attempts: 7, want: 600 * time.Second},
+		{name: "max for many attempts", backoffSec: 10, maxBackoffSec: 600, attempts: 100, want: 600 * time.Second},
+		{name: "no max", backoffSec: 1, attempts: 11, want: 1024 * time.Second},
+		{name: "backoff over max", backoffSec: 60, maxBackoffSec: 30, attempts: 1, want: 30 * time.Second},
+		{name: "default backoff", maxBackoffSec: 600, attempts: 2, want: 20 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			common.CONF.Webhook.Retry.BackoffSec = tt.backoffSec
+			common.CONF.Webhook.Retry.MaxBackoffSec = tt.maxBackoffSec
+			if got := retryBackoff(tt.attempts); got != tt.want {
+				t.Errorf("retryBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	defaultRetry := common.CONF.Webhook.Retry
+	defer func() { common.CONF.Webhook.Retry = defaultRetry }()
+
+	tests := []struct {
+		maxAttempts int
+		want        int
+	}{
+		{maxAttempts: 3, want: 3},
+		{maxAttempts: 0, want: 5},
+		{maxAttempts: -1, want: 5},
+	}
+	for _, tt := range tests {
+		common.CONF.Webhook.Retry.MaxAttempts = tt.maxAttempts
+		if got := retryMaxAttempts(); got != tt.want {
+			t.Errorf("retryMaxAttempts() with %d = %d, want %d", tt.maxAttempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gywn/webhook-go/common"
+	"github.com/go-gywn/webhook-go/model"
+	t "github.com/prometheus/alertmanager/template"
+)
+
+// defaultTemplateName template of webhook.template, others are <templateDir>/<name>.tpl
+const defaultTemplateName = "default"
+
+var templateMtx = &sync.RWMutex{}
+var hookTemplates = map[string]*template.Template{}
+
+// templateFailures template execution failures, default template is sent instead
+var templateFailures uint64
+
+var templateNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// templatePath file path of template name
+func templatePath(name string) (string, error) {
+	if name == "" || name == defaultTemplateName {
+		return common.CONF.Webhook.Template, nil
+	}
+	if !templateNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid template name '%s'", name)
+	}
+	if common.CONF.Webhook.TemplateDir == "" {
+		return "", fmt.Errorf("templateDir is not set")
+	}
+	return filepath.Join(common.CONF.Webhook.TemplateDir, name+".tpl"), nil
+}
+
+// loadTemplates load default template and all templates in templateDir,
+// built-in template is used if default template fails
+func loadTemplates() {
+	if err := loadTemplate(defaultTemplateName); err != nil {
+		logger.Error("template > ", err)
+		tpl, _ := parseTemplate("template", defaultTemplate)
+		setTemplate(defaultTemplateName, tpl)
+	}
+
+	for _, name := range storedTemplateNames() {
+		if err := loadTemplate(name); err != nil {
+			logger.Error("template > ", err)
+		}
+	}
+}
+
+// storedTemplateNames template names in templateDir and in database, except default
+func storedTemplateNames() (names []string) {
+	all := templateFileNames()
+	if saved, err := model.GetTemplateNames(); err == nil {
+		all = append(all, saved...)
+	}
+	var seen = map[string]bool{defaultTemplateName: true}
+	for _, name := range all {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// reloadTemplate save template file as new active version if it differs, then load active version,
+// invalid file is not saved and returns error
+func reloadTemplate(name string, author string) error {
+	path, err := templatePath(name)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = defaultTemplateName
+	}
+
+	content := fileUtil.ReadFile(path)
+	if strings.TrimSpace(content) != "" {
+		active := &model.HookTemplate{Name: name}
+		found, err := active.GetActive()
+		if err != nil {
+			return err
+		}
+		if !found || active.Content != content {
+			if err = validateTemplate(content); err != nil {
+				return fmt.Errorf("template '%s' - %s - %s", name, path, err)
+			}
+			if err = writeTemplate(name, content, author, "reloaded from "+path); err != nil {
+				return err
+			}
+		}
+	}
+	return loadTemplate(name)
+}
+
+// reloadTemplates reload default and all stored templates, failed names are returned in error
+func reloadTemplates(author string) error {
+	var failed []string
+	for _, name := range append([]string{defaultTemplateName}, storedTemplateNames()...) {
+		if err := reloadTemplate(name, author); err != nil {
+			logger.Error("template > ", err)
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("templates not reloaded - %s", strings.Join(failed, ","))
+	}
+	return nil
+}
+
+// loadTemplate parse active version of name, current template is kept if it fails
+func loadTemplate(name string) error {
+	content, err := readTemplate(name)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("template '%s' is empty or not found", name)
+	}
+	tpl, err := parseTemplate("template", content)
+	if err != nil {
+		return err
+	}
+	setTemplate(name, tpl)
+	return nil
+}
+
+func setTemplate(name string, tpl *template.Template) {
+	if name == "" {
+		name = defaultTemplateName
+	}
+	templateMtx.Lock()
+	defer templateMtx.Unlock()
+	hookTemplates[name] = tpl
+}
+
+// getTemplate template of name, default template if not loaded
+func getTemplate(name string) *template.Template {
+	templateMtx.RLock()
+	defer templateMtx.RUnlock()
+	if tpl, ok := hookTemplates[name]; ok {
+		return tpl
+	}
+	if tpl, ok := hookTemplates[defaultTemplateName]; ok {
+		return tpl
+	}
+	return hookDefaultTemplate
+}
+
+// hasTemplate template of name is loaded
+func hasTemplate(name string) bool {
+	templateMtx.RLock()
+	defer templateMtx.RUnlock()
+	_, ok := hookTemplates[name]
+	return ok
+}
+
+// getTemplateNames loaded template names
+func getTemplateNames() []string {
+	templateMtx.RLock()
+	defer templateMtx.RUnlock()
+	var names []string
+	for name := range hookTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateFileNames template names in templateDir
+func templateFileNames() (names []string) {
+	dir := common.CONF.Webhook.TemplateDir
+	if dir == "" {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(fileUtil.GetFilePath(dir), "*.tpl"))
+	if err != nil {
+		logger.Error("template > ", err)
+		return
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".tpl")
+		if name != defaultTemplateName && templateNameRegexp.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// alertTemplateName template of alertname, then level, then default
+func alertTemplateName(alert t.Alert) string {
+	conf := common.CONF.Webhook.Templates
+	if name, ok := conf.Alertnames[alert.Labels[labelAlertname]]; ok {
+		return name
+	}
+	if name, ok := conf.Levels[alert.Labels[labelLevel]]; ok {
+		return name
+	}
+	return defaultTemplateName
+}
+
+// renderMessage apply template, if error render default template from clean buffer and count failure
+func renderMessage(name string, vars map[string]interface{}) (string, error) {
+	var messageBuffer bytes.Buffer
+	err := getTemplate(name).Execute(&messageBuffer, vars)
+	if err == nil {
+		return messageBuffer.String(), nil
+	}
+
+	atomic.AddUint64(&templateFailures, 1)
+	err = fmt.Errorf("template '%s' - %s", name, err)
+	logger.Error("template > ", err)
+
+	messageBuffer.Reset()
+	hookDefaultTemplate.Execute(&messageBuffer, vars)
+	return messageBuffer.String(), err
+}
+
+// TemplateStats template execution failures and rejected file changes since start
+type TemplateStats struct {
+	Failures     uint64             `json:"failures"`
+	Rejected     uint64             `json:"rejected"`
+	LastRejected *TemplateRejection `json:"last_rejected"`
+}
+
+func getTemplateStats() TemplateStats {
+	watcherMtx.Lock()
+	defer watcherMtx.Unlock()
+	return TemplateStats{
+		Failures:     atomic.LoadUint64(&templateFailures),
+		Rejected:     rejectedTemplates,
+		LastRejected: lastRejection,
+	}
+}
+
+// readTemplate active version from database, the first version is saved from template file
+func readTemplate(name string) (content string, err error) {
+	path, err := templatePath(name)
+	if err != nil {
+		return
+	}
+	if name == "" {
+		name = defaultTemplateName
+	}
+
+	version := &model.HookTemplate{Name: name}
+	found, err := version.GetActive()
+	if err != nil {
+		return
+	}
+	if found {
+		return version.Content, nil
+	}
+
+	logger.Debug("read template file ", path)
+	content = fileUtil.ReadFile(path)
+	if strings.TrimSpace(content) != "" {
+		version.Content = content
+		version.Author = "file"
+		version.Comment = "from " + path
+		version.Insert()
+	}
+	return
+}
+
+func checkTemplate(content string) (err error) {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("template content is null")
+	}
+	_, err = parseTemplate("template", content)
+	return
+}
+
+// checkNamedTemplate check template name, and render sample alert with content as reload and watcher do
+func checkNamedTemplate(name string, content string) error {
+	if _, err := templatePath(name); err != nil {
+		return err
+	}
+	if name == "" {
+		name = defaultTemplateName
+	}
+	if err := validateTemplate(content); err != nil {
+		return fmt.Errorf("template '%s' - %s", name, err)
+	}
+	return nil
+}
+
+// writeTemplate save new active version, and template file too
+func writeTemplate(name string, content string, author string, comment string) (err error) {
+	path, err := templatePath(name)
+	if err != nil {
+		return
+	}
+	if name == "" {
+		name = defaultTemplateName
+	}
+	if err = checkTemplate(content); err != nil {
+		return
+	}
+
+	version := &model.HookTemplate{Name: name, Content: content, Author: author, Comment: comment}
+	if err = version.Insert(); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	return ioutil.WriteFile(path, []byte(content), 0644)
+}
+
+// templateAuthor author param, client ip if empty
+func templateAuthor(c *gin.Context) string {
+	if author, _ := c.GetPostForm("author"); author != "" {
+		return author
+	}
+	return c.ClientIP()
+}
+
+// rollbackTemplate save old version as new active version and reload
+func rollbackTemplate(name string, version int, author string, comment string) error {
+	if name == "" {
+		name = defaultTemplateName
+	}
+	old := &model.HookTemplate{Name: name, Version: version}
+	if err := old.Get(); err != nil {
+		return err
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("rollback to version %d", version)
+	}
+	if err := writeTemplate(name, old.Content, author, comment); err != nil {
+		return err
+	}
+	return loadTemplate(name)
+}
+
+// diffTemplate line diff of two versions, active version if to is 0
+func diffTemplate(name string, from int, to int) ([]string, error) {
+	if name == "" {
+		name = defaultTemplateName
+	}
+	a := &model.HookTemplate{Name: name, Version: from}
+	if err := a.Get(); err != nil {
+		return nil, err
+	}
+
+	b := &model.HookTemplate{Name: name, Version: to}
+	if to == 0 {
+		if found, err := b.GetActive(); err != nil || !found {
+			return nil, fmt.Errorf("template '%s' has no active version", name)
+		}
+	} else if err := b.Get(); err != nil {
+		return nil, err
+	}
+	return diffLines(a.Content, b.Content), nil
+}
+
+// renderTemplate render messages of stored hook or alertmanager payload,
+// content is rendered if not empty, or template selected as sending
+func renderTemplate(content string, hookID string, payload string) ([]string, error) {
+	var tpl *template.Template
+	if content != "" {
+		var err error
+		if tpl, err = parseTemplate("template", content); err != nil {
+			return nil, err
+		}
+	}
+
+	if hookID != "" {
+		detail := &model.HookDetail{HookID: hookID}
+		if err := detail.GetLatest(); err != nil {
+			return nil, err
+		}
+		payload = detail.ReqJSON
+	}
+	if payload == "" {
+		return nil, fmt.Errorf("hook_id or payload is required")
+	}
+
+	task, err := decodeTask(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// same as sending, one message per alert if not group mode
+	var alerts []t.Alert
+	var varsList []map[string]interface{}
+	switch {
+	case task.data != nil && common.CONF.Webhook.GroupMode:
+		alert := groupAlert(task.data.Data)
+		alerts = append(alerts, alert)
+		varsList = append(varsList, groupVars(task.data.Data, alert))
+	case task.data != nil:
+		for _, alert := range task.data.Alerts {
+			alerts = append(alerts, alert)
+			varsList = append(varsList, alertVars(alert))
+		}
+	default:
+		alerts = append(alerts, task.alert)
+		varsList = append(varsList, alertVars(task.alert))
+	}
+
+	var messages []string
+	for i, vars := range varsList {
+		alertTpl := tpl
+		if alertTpl == nil {
+			alertTpl = getTemplate(alertTemplateName(alerts[i]))
+		}
+		var buf bytes.Buffer
+		if err = alertTpl.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("alert %d - %s", i, err)
+		}
+		messages = append(messages, buf.String())
+	}
+	return messages, nil
+}
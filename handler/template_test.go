@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckNamedTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tplName string
+		content string
+		wantErr string
+	}{
+		{name: "default", content: "[{{ .status }}] {{ .summary }}"},
+		{name: "empty content", content: " ", wantErr: "template content is null"},
+		{name: "parse error", content: "{{ .status ", wantErr: "unclosed action"},
+		{name: "sample alert error", content: `{{ .startsAt.Format "15:04" }} {{ .summary.Missing }}`, wantErr: "template 'default' - sample alert"},
+		{name: "invalid name", tplName: "../sms", content: "{{ .status }}", wantErr: "invalid template name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNamedTemplate(tt.tplName, tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkNamedTemplate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkNamedTemplate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	defaultTpl := hookDefaultTemplate
+	defer func() { hookDefaultTemplate = defaultTpl }()
+	hookDefaultTemplate, _ = parseTemplate("default_template", "[{{ .status }}] default")
+
+	for name, content := range map[string]string{
+		"render-ok":     "[{{ .status }}] {{ .summary }}",
+		"render-failed": "[{{ .status }}] partial {{ .summary.Missing }}",
+	} {
+		tpl, err := parseTemplate(name, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setTemplate(name, tpl)
+		defer func(name string) {
+			templateMtx.Lock()
+			delete(hookTemplates, name)
+			templateMtx.Unlock()
+		}(name)
+	}
+
+	tests := []struct {
+		name         string
+		tplName      string
+		want         string
+		wantErr      bool
+		wantFailures uint64
+	}{
+		{name: "rendered", tplName: "render-ok", want: "[firing] mysql is down"},
+		{name: "failed template to default from clean buffer", tplName: "render-failed", want: "[firing] default", wantErr: true, wantFailures: 1},
+		{name: "not loaded template to default", tplName: "render-unknown", want: "[firing] default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := getTemplateStats().Failures
+			got, err := renderMessage(tt.tplName, map[string]interface{}{"status": "firing", "summary": "mysql is down"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "template '"+tt.tplName+"'") {
+				t.Errorf("renderMessage() error = %v, want template name", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderMessage() = %q, want %q", got, tt.want)
+			}
+			if n := getTemplateStats().Failures - failures; n != tt.wantFailures {
+				t.Errorf("failures = %d, want %d", n, tt.wantFailures)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-gywn/webhook-go/common"
+	"github.com/go-gywn/webhook-go/model"
+	t "github.com/prometheus/alertmanager/template"
+)
+
+// TemplateRejection template file change not applied
+type TemplateRejection struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Error      string    `json:"error"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+var watcherMtx = &sync.Mutex{}
+var rejectedTemplates uint64
+var lastRejection *TemplateRejection
+
+// templateFile last seen file state
+type templateFile struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+// startTemplateWatcher poll template files, apply changed one if it parses and renders a sample alert
+func startTemplateWatcher() {
+	interval := time.Duration(common.CONF.Webhook.TemplateWatch.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	go func() {
+		files := map[string]templateFile{}
+		for {
+			names := append([]string{defaultTemplateName}, templateFileNames()...)
+			for _, name := range names {
+				watchTemplate(name, files)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// watchTemplate check template file of name, files keeps last seen state by path
+func watchTemplate(name string, files map[string]templateFile) {
+	path, err := templatePath(name)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(fileUtil.GetFilePath(path))
+	if err != nil {
+		return
+	}
+
+	// mtime and size first, then hash of content
+	last, ok := files[path]
+	if ok && last.modTime.Equal(info.ModTime()) && last.size == info.Size() {
+		return
+	}
+	content := fileUtil.ReadFile(path)
+	file := templateFile{modTime: info.ModTime(), size: info.Size(), hash: crypt.MD5(content)}
+	files[path] = file
+	if ok && last.hash == file.hash {
+		return
+	}
+
+	// broken file is rejected before it is compared with active version
+	if err = validateTemplate(content); err != nil {
+		rejectTemplate(name, path, err)
+		return
+	}
+
+	// same as active version, saved by API or already applied
+	active := &model.HookTemplate{Name: name}
+	found, err := active.GetActive()
+	if err != nil {
+		return
+	}
+	changed := !found || active.Content != content
+	if !changed && hasTemplate(name) {
+		return
+	}
+	if changed {
+		if err = writeTemplate(name, content, "watcher", "changed on disk - "+path); err != nil {
+			rejectTemplate(name, path, err)
+			return
+		}
+	}
+	if err = loadTemplate(name); err != nil {
+		rejectTemplate(name, path, err)
+		return
+	}
+	logger.Info("template watcher > ", "reloaded template '", name, "' - ", path)
+}
+
+// validateTemplate parse and render sample alert
+func validateTemplate(content string) error {
+	if err := checkTemplate(content); err != nil {
+		return err
+	}
+	tpl, err := parseTemplate("template", content)
+	if err != nil {
+		return err
+	}
+
+	alert := sampleAlert()
+	vars := alertVars(alert)
+	if common.CONF.Webhook.GroupMode {
+		data := t.Data{
+			Receiver:          "webhook",
+			Status:            alert.Status,
+			Alerts:            t.Alerts{alert},
+			GroupLabels:       t.KV{labelAlertname: alert.Labels[labelAlertname]},
+			CommonLabels:      alert.Labels,
+			CommonAnnotations: alert.Annotations,
+			ExternalURL:       "http://127.0.0.1:9093",
+		}
+		vars = groupVars(data, groupAlert(data))
+	}
+
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, vars); err != nil {
+		return fmt.Errorf("sample alert - %s", err)
+	}
+	return nil
+}
+
+// sampleAlert firing alert with every mapped label and annotation
+func sampleAlert() t.Alert {
+	now := time.Now()
+	alert := t.Alert{
+		Status:       "firing",
+		Labels:       t.KV{},
+		Annotations:  t.KV{},
+		StartsAt:     now.Add(-time.Hour),
+		EndsAt:       now,
+		GeneratorURL: "http://127.0.0.1:9090/graph",
+		Fingerprint:  "0123456789abcdef",
+	}
+	for k, v := range common.CONF.Webhook.LabelMapper {
+		alert.Labels[v] = "sample_" + k
+	}
+	for k, v := range common.CONF.Webhook.AnnotationMapper {
+		alert.Annotations[v] = "sample " + k
+	}
+	return alert
+}
+
+// rejectTemplate log and keep the last rejected change
+func rejectTemplate(name string, path string, err error) {
+	logger.Error("template watcher > ", "rejected template '", name, "' - ", path, " - ", err)
+	watcherMtx.Lock()
+	defer watcherMtx.Unlock()
+	rejectedTemplates++
+	lastRejection = &TemplateRejection{Name: name, Path: path, Error: err.Error(), RejectedAt: time.Now()}
+}
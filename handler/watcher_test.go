@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-gywn/webhook-go/common"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	defaultWebhook := common.CONF.Webhook
+	defer func() { common.CONF.Webhook = defaultWebhook }()
+
+	tests := []struct {
+		name      string
+		content   string
+		groupMode bool
+		wantErr   string
+	}{
+		{name: "valid", content: `[{{ .status }}] {{ .summary }} {{ .startsAt.Format "15:04" }}`},
+		{name: "empty", content: "\n", wantErr: "template content is null"},
+		{name: "parse error", content: "{{ if .status }}", wantErr: "unexpected EOF"},
+		{name: "fails on sample alert", content: "{{ .summary }} {{ .startsAt.Missing }}", wantErr: "sample alert"},
+		{name: "group vars without group mode", content: "{{ len .Alerts }} alerts", wantErr: "sample alert"},
+		{name: "group vars in group mode", content: "{{ len .Alerts }} alerts of {{ .Receiver }}", groupMode: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			common.CONF.Webhook.GroupMode = tt.groupMode
+			err := validateTemplate(tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateTemplate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateTemplate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRejectTemplate(t *testing.T) {
+	before := getTemplateStats().Rejected
+	rejectTemplate("sms", "templates/sms.tpl", validateTemplate("{{ .startsAt.Missing }}"))
+
+	stats := getTemplateStats()
+	if stats.Rejected != before+1 {
+		t.Errorf("rejected = %d, want %d", stats.Rejected, before+1)
+	}
+	if last := stats.LastRejected; last == nil || last.Name != "sms" || last.Path != "templates/sms.tpl" || !strings.Contains(last.Error, "sample alert") {
+		t.Errorf("last rejected = %+v", last)
+	}
+}
+
+func TestWatchTemplateRejected(t *testing.T) {
+	defaultWebhook := common.CONF.Webhook
+	defer func() { common.CONF.Webhook = defaultWebhook }()
+	common.CONF.Webhook.TemplateDir = t.TempDir()
+
+	path := filepath.Join(common.CONF.Webhook.TemplateDir, "sms.tpl")
+	if err := ioutil.WriteFile(path, []byte("[{{ .status }}] {{ .startsAt.Missing }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// parsed but failed on sample alert, rejected once until the file changes again
+	files := map[string]templateFile{}
+	before := getTemplateStats().Rejected
+	watchTemplate("sms", files)
+	watchTemplate("sms", files)
+
+	stats := getTemplateStats()
+	if stats.Rejected != before+1 {
+		t.Errorf("rejected = %d, want %d", stats.Rejected, before+1)
+	}
+	if last := stats.LastRejected; last == nil || last.Name != "sms" || last.Path != path || !strings.Contains(last.Error, "sample alert") {
+		t.Errorf("last rejected = %+v", last)
+	}
+	if hasTemplate("sms") {
+		t.Error("rejected template is loaded")
+	}
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
+	"os/signal"
+	"syscall"
+
 	"github.com/go-gywn/goutil"
 	"github.com/go-gywn/webhook-go/handler"
 	"github.com/go-gywn/webhook-go/model"
 	"golang.org/x/sync/errgroup"
 )
 
-var g errgroup.Group
 var logger = goutil.GetLogger()
 
 func init() {
@@ -17,11 +20,21 @@ func init() {
 }
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
 		return handler.StartHandler()
 	})
 
+	// graceful shutdown on signal
+	g.Go(func() error {
+		<-ctx.Done()
+		logger.Info("Shutdown start")
+		return handler.StopHandler()
+	})
+
 	if err := g.Wait(); err != nil {
 		logger.Panic("Startup failed", err)
 	}
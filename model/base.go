@@ -53,7 +53,12 @@ func OpenDatabase() {
 	var syncTargets = []interface{}{
 		&Hook{},
 		&HookDetail{},
+		&HookDelivery{},
 		&HookIgnore{},
+		&HookOutbox{},
+		&HookDeadLetter{},
+		&HookQueue{},
+		&HookTemplate{},
 	}
 	if err = db.AutoMigrate(syncTargets...); err != nil {
 		logger.Fatal("db.AutoMigrate failed - ", err)
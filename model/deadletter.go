@@ -0,0 +1,85 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// dead letter status
+const (
+	DeadLetterNew      = "new"
+	DeadLetterRequeued = "requeued"
+)
+
+// HookDeadLetter alert can not be routed or delivered
+type HookDeadLetter struct {
+	ID           int       `form:"id"             json:"id"`
+	HookID       string    `form:"hook_id"        json:"hook_id"        gorm:"column:hook_id;        type:varchar(32) not null default ''; index:ix_hookid"`
+	HookDetailID int       `form:"hook_detail_id" json:"hook_detail_id" gorm:"column:hook_detail_id; type:int not null default 0"`
+	Target       string    `form:"target"         json:"target"         gorm:"column:target;         type:varchar(64) not null default ''"`
+	Status       string    `form:"status"         json:"status"         gorm:"column:status;         type:varchar(10) not null default 'new'; index:ix_status"`
+	Recipients   string    `json:"recipients"     gorm:"column:recipients;     type:varchar(1000) not null default ''"`
+	Reason       string    `json:"reason"         gorm:"column:reason;         type:text not null"`
+	ReqJSON      string    `json:"req_json"       gorm:"column:req_json;       type:json not null"`
+	Message      string    `json:"message"        gorm:"column:message;        type:text not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Insert new dead letter
+func (o *HookDeadLetter) Insert() error {
+	o.Status = DeadLetterNew
+	if result := db.Create(o); result.Error != nil {
+		logger.Error("HookDeadLetter.Insert() > ", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// Get dead letter by id
+func (o *HookDeadLetter) Get() error {
+	if o.ID == 0 {
+		return fmt.Errorf("id empty")
+	}
+	if result := db.First(o, o.ID); result.Error != nil {
+		logger.Error("HookDeadLetter.Get() > ", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// SetStatus change dead letter status only if current status is from, false if status is changed already
+func (o *HookDeadLetter) SetStatus(from, to string) (bool, error) {
+	result := db.Model(&HookDeadLetter{}).Where("id = ? and status = ?", o.ID, from).Updates(map[string]interface{}{"status": to, "updated_at": time.Now()})
+	if result.Error != nil {
+		logger.Error("HookDeadLetter.SetStatus() > ", result.Error)
+		return false, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return false, nil
+	}
+	o.Status = to
+	return true, nil
+}
+
+// GetList dead letter list
+func (o *HookDeadLetter) GetList(limit int) (r []HookDeadLetter, err error) {
+	logger.Debug("HookDeadLetter.GetList() start")
+
+	clauseMap := map[string]interface{}{}
+	if o.HookID != "" {
+		clauseMap["hook_id"] = o.HookID
+	}
+	if o.Target != "" {
+		clauseMap["target"] = o.Target
+	}
+	if o.Status != "" {
+		clauseMap["status"] = o.Status
+	}
+
+	if result := db.Where(clauseMap).Order("id desc").Limit(limit).Find(&r); result.Error != nil {
+		logger.Error(result.Error)
+		err = result.Error
+	}
+	return
+}
@@ -2,7 +2,7 @@ package model
 
 import "reflect"
 
-// GetUpsertAllColumns return all column names
+// GetUpsertAllColumns return all column names, without relation fields
 func GetUpsertAllColumns(value interface{}) []string {
 	tx := db.Model(value)
 	el := reflect.ValueOf(value).Elem()
@@ -10,7 +10,8 @@ func GetUpsertAllColumns(value interface{}) []string {
 	for i := 0; i < el.NumField(); i++ {
 		t := el.Type().Field(i)
 		f := tx.Statement.Schema.ParseField(t)
-		if !f.Updatable {
+		// relation field like HookDetails is not a column
+		if !f.Updatable || f.DataType == "" {
 			continue
 		}
 		if f.DBName == "" {
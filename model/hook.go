@@ -27,12 +27,29 @@ type Hook struct {
 
 // HookDetail hook detail
 type HookDetail struct {
-	ID        int
-	HookID    string    `json:"hook_id"       gorm:"column:hook_id;      type:varchar(32) not null default ''; index:ix_hookid"`
-	Status    string    `json:"status"        gorm:"column:status;       type:varchar(10) not null default '';"`
-	ReqJSON   string    `json:"req_json"      gorm:"column:req_json;     type:json not null"`
-	Message   string    `json:"message"       gorm:"column:message;      type:text not null"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             int
+	HookID         string         `json:"hook_id"         gorm:"column:hook_id;        type:varchar(32) not null default ''; index:ix_hookid"`
+	Status         string         `json:"status"          gorm:"column:status;         type:varchar(10) not null default '';"`
+	ReqJSON        string         `json:"req_json"        gorm:"column:req_json;       type:json not null"`
+	Message        string         `json:"message"         gorm:"column:message;        type:text not null"`
+	Route          string         `json:"route"           gorm:"column:route;          type:varchar(255) not null default ''"`
+	TemplateError  string         `json:"template_error"  gorm:"column:template_error; type:text not null"`
+	HookDeliveries []HookDelivery `json:"hook_deliveries" gorm:"foreignKey:HookDetailID"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// HookDelivery delivery result of hook detail to target
+type HookDelivery struct {
+	ID           int
+	HookDetailID int       `json:"hook_detail_id" gorm:"column:hook_detail_id; type:int not null default 0; index:ix_detail"`
+	HookID       string    `json:"hook_id"        gorm:"column:hook_id;        type:varchar(32) not null default ''; index:ix_hookid"`
+	Target       string    `json:"target"         gorm:"column:target;         type:varchar(64) not null default ''"`
+	Attempt      int       `json:"attempt"        gorm:"column:attempt;        type:int not null default 0"`
+	Code         int       `json:"code"           gorm:"column:code;           type:int not null default 0"`
+	Response     string    `json:"response"       gorm:"column:response;       type:text not null"`
+	Error        string    `json:"error"          gorm:"column:error;          type:text not null"`
+	DurationMs   int64     `json:"duration_ms"    gorm:"column:duration_ms;    type:bigint not null default 0"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 // Upsert insert on duplicate update
@@ -54,8 +71,8 @@ func (o *Hook) Upsert(hookColumns ...string) error {
 		hookDetailColumns := GetUpsertAllColumns(&HookDetail{})
 		logger.Debug("HookDetail.Upsert() > ", "columns ", hookDetailColumns)
 		hookDetailClause := clause.OnConflict{DoUpdates: clause.AssignmentColumns(hookDetailColumns)}
-		for _, hookDetail := range o.HookDetails {
-			if result := db.Clauses(hookDetailClause).Create(&hookDetail); result.Error != nil {
+		for i := range o.HookDetails {
+			if result := db.Clauses(hookDetailClause).Create(&o.HookDetails[i]); result.Error != nil {
 				logger.Error("HookDetail.Upsert() > ", result.Error)
 			}
 		}
@@ -96,13 +113,22 @@ func (o *Hook) GetList(limit int) (r []Hook, err error) {
 
 	tx = tx.Order("starts_at desc").Limit(limit)
 
-	if result := tx.Preload(clause.Associations).Find(&r); result.Error != nil {
+	if result := tx.Preload(clause.Associations).Preload("HookDetails.HookDeliveries").Find(&r); result.Error != nil {
 		logger.Error(result.Error)
 		err = result.Error
 	}
 	return
 }
 
+// Insert insert delivery result
+func (o *HookDelivery) Insert() error {
+	if result := db.Create(o); result.Error != nil {
+		logger.Error("HookDelivery.Insert() > ", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
 // HookIgnore hook ignore target
 type HookIgnore struct {
 	Instance  string     `form:"instance"    json:"instance"      gorm:"column:instance;     type:varchar(32) not null default '*'; primaryKey"`
@@ -334,8 +360,8 @@ func (o *Notification) CheckForm() error {
 		return fmt.Errorf("instance empty")
 	}
 
-	if _, ok := common.CONF.Webhook.Targets[o.Level]; !ok {
-		return fmt.Errorf("level '" + o.Level + "' not in target")
+	if len(common.CONF.Webhook.Routes) == 0 && len(common.CONF.Webhook.DefaultTargets) == 0 && len(common.CONF.Webhook.GetTargetNames(o.Level)) == 0 {
+		return fmt.Errorf("level '%s' not in target", o.Level)
 	}
 
 	if strings.TrimSpace(o.Message) == "" {
@@ -351,3 +377,28 @@ func (o *Notification) CheckForm() error {
 
 	return err
 }
+
+// GetLatest latest hook detail of hook id
+func (o *HookDetail) GetLatest() error {
+	if o.HookID == "" {
+		return fmt.Errorf("hook_id empty")
+	}
+	result := db.Where("hook_id = ?", o.HookID).Order("id desc").Limit(1).Find(o)
+	if result.Error != nil {
+		logger.Error("HookDetail.GetLatest() > ", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("hook '%s' not found", o.HookID)
+	}
+	return nil
+}
+
+// GetTemplateFailures hook details failed to apply template, latest first
+func (o *HookDetail) GetTemplateFailures(limit int) (r []HookDetail, err error) {
+	if result := db.Where("template_error <> ''").Order("id desc").Limit(limit).Find(&r); result.Error != nil {
+		logger.Error("HookDetail.GetTemplateFailures() > ", result.Error)
+		err = result.Error
+	}
+	return
+}
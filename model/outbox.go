@@ -0,0 +1,106 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// outbox status
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+)
+
+// HookOutbox failed delivery to retry
+type HookOutbox struct {
+	ID           int        `form:"id"             json:"id"`
+	HookID       string     `form:"hook_id"        json:"hook_id"        gorm:"column:hook_id;        type:varchar(32) not null default ''; index:ix_hookid"`
+	HookDetailID int        `form:"hook_detail_id" json:"hook_detail_id" gorm:"column:hook_detail_id; type:int not null default 0"`
+	Target       string     `form:"target"         json:"target"         gorm:"column:target;         type:varchar(64) not null default ''"`
+	Status       string     `form:"status"         json:"status"         gorm:"column:status;         type:varchar(10) not null default 'pending'; index:ix_status,priority:1"`
+	Recipients   string     `json:"recipients"     gorm:"column:recipients;     type:varchar(1000) not null default ''"`
+	Attempts     int        `json:"attempts"       gorm:"column:attempts;       type:int not null default 0"`
+	ReqJSON      string     `json:"req_json"       gorm:"column:req_json;       type:json not null"`
+	Message      string     `json:"message"        gorm:"column:message;        type:text not null"`
+	LastError    string     `json:"last_error"     gorm:"column:last_error;     type:text not null"`
+	NextRetryAt  *time.Time `json:"next_retry_at"  gorm:"column:next_retry_at;  type:datetime(3) null; index:ix_status,priority:2"`
+	ClaimedBy    string     `json:"claimed_by"     gorm:"column:claimed_by;     type:varchar(128) not null default ''"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Insert new pending outbox, first attempt is already failed
+func (o *HookOutbox) Insert(backoff time.Duration) error {
+	nextRetryAt := time.Now().Add(backoff)
+	o.Status = OutboxPending
+	o.Attempts = 1
+	o.NextRetryAt = &nextRetryAt
+	if result := db.Create(o); result.Error != nil {
+		logger.Error("HookOutbox.Insert() > ", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// Update update retry result
+func (o *HookOutbox) Update() error {
+	result := db.Model(o).Select("status", "attempts", "recipients", "last_error", "next_retry_at", "updated_at").Updates(o)
+	if result.Error != nil {
+		logger.Error("HookOutbox.Update() > ", result.Error)
+	}
+	return result.Error
+}
+
+// ClaimRetryList claim pending outbox to retry now, next_retry_at is moved by lease
+// so other instances skip it, and it is retried again if the owner dies
+func ClaimRetryList(owner string, limit int, lease time.Duration) (r []HookOutbox, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? and next_retry_at <= ?", OutboxPending, now).
+			Order("next_retry_at").Limit(limit).Find(&r)
+		if result.Error != nil || len(r) == 0 {
+			return result.Error
+		}
+
+		var ids []int
+		leaseUntil := now.Add(lease)
+		for i := range r {
+			r[i].ClaimedBy = owner
+			r[i].NextRetryAt = &leaseUntil
+			ids = append(ids, r[i].ID)
+		}
+		return tx.Model(&HookOutbox{}).Where("id in ?", ids).
+			Updates(map[string]interface{}{"claimed_by": owner, "next_retry_at": leaseUntil}).Error
+	})
+	if err != nil {
+		logger.Error("ClaimRetryList() > ", err)
+		r = nil
+	}
+	return
+}
+
+// GetList outbox list
+func (o *HookOutbox) GetList(limit int) (r []HookOutbox, err error) {
+	logger.Debug("HookOutbox.GetList() start")
+
+	clauseMap := map[string]interface{}{}
+	if o.HookID != "" {
+		clauseMap["hook_id"] = o.HookID
+	}
+	if o.Target != "" {
+		clauseMap["target"] = o.Target
+	}
+	if o.Status != "" {
+		clauseMap["status"] = o.Status
+	}
+
+	if result := db.Where(clauseMap).Order("id desc").Limit(limit).Find(&r); result.Error != nil {
+		logger.Error(result.Error)
+		err = result.Error
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HookQueue alert waiting to be processed
+type HookQueue struct {
+	ID        int        `json:"id"`
+	HookID    string     `json:"hook_id"    gorm:"column:hook_id;    type:varchar(32) not null default ''"`
+	ReqJSON   string     `json:"req_json"   gorm:"column:req_json;   type:json not null"`
+	ClaimedBy string     `json:"claimed_by" gorm:"column:claimed_by; type:varchar(128) not null default ''"`
+	ClaimedAt *time.Time `json:"claimed_at" gorm:"column:claimed_at; type:datetime(3) null; index:ix_claimedat"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InsertHookQueues insert alerts to queue
+func InsertHookQueues(queues []HookQueue) error {
+	if len(queues) == 0 {
+		return nil
+	}
+	if result := db.Create(&queues); result.Error != nil {
+		logger.Error("InsertHookQueues() > ", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// ClaimHookQueues claim unclaimed or lease expired alerts in arrival order, locked rows of other instances are skipped
+func ClaimHookQueues(owner string, limit int, lease time.Duration) (r []HookQueue, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("claimed_at is null or claimed_at < ?", now.Add(-lease)).
+			Order("id").Limit(limit).Find(&r)
+		if result.Error != nil || len(r) == 0 {
+			return result.Error
+		}
+
+		var ids []int
+		for i := range r {
+			r[i].ClaimedBy = owner
+			r[i].ClaimedAt = &now
+			ids = append(ids, r[i].ID)
+		}
+		return tx.Model(&HookQueue{}).Where("id in ?", ids).
+			Updates(map[string]interface{}{"claimed_by": owner, "claimed_at": now}).Error
+	})
+	if err != nil {
+		logger.Error("ClaimHookQueues() > ", err)
+		r = nil
+	}
+	return
+}
+
+// ReleaseHookQueues release claimed alerts to be claimed again
+func ReleaseHookQueues(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	result := db.Model(&HookQueue{}).Where("id in ?", ids).
+		Updates(map[string]interface{}{"claimed_by": "", "claimed_at": nil})
+	if result.Error != nil {
+		logger.Error("ReleaseHookQueues() > ", result.Error)
+	}
+	return result.Error
+}
+
+// CountHookQueues stored alerts
+func CountHookQueues() (count int64, err error) {
+	if result := db.Model(&HookQueue{}).Count(&count); result.Error != nil {
+		logger.Error("CountHookQueues() > ", result.Error)
+		err = result.Error
+	}
+	return
+}
+
+// GetList queued alerts in arrival order
+func (o *HookQueue) GetList(limit int) (r []HookQueue, err error) {
+	if result := db.Order("id").Limit(limit).Find(&r); result.Error != nil {
+		logger.Error("HookQueue.GetList() > ", result.Error)
+		err = result.Error
+	}
+	return
+}
+
+// Delete delete processed alert
+func (o *HookQueue) Delete() error {
+	result := db.Delete(o)
+	if result.Error != nil {
+		logger.Error("HookQueue.Delete() > ", result.Error)
+	}
+	return result.Error
+}
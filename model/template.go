@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HookTemplate template version, only one active version per name
+type HookTemplate struct {
+	ID        int       `form:"id"       json:"id"`
+	Name      string    `form:"name"     json:"name"    gorm:"column:name;    type:varchar(64) not null default ''; uniqueIndex:ux_name_version,priority:1"`
+	Version   int       `form:"version"  json:"version" gorm:"column:version; type:int not null default 0; uniqueIndex:ux_name_version,priority:2"`
+	Content   string    `json:"content"                 gorm:"column:content; type:text not null"`
+	Author    string    `form:"author"   json:"author"  gorm:"column:author;  type:varchar(64) not null default ''"`
+	Comment   string    `form:"comment"  json:"comment" gorm:"column:comment; type:varchar(255) not null default ''"`
+	Active    string    `json:"active"                  gorm:"column:active;  type:varchar(1) not null default 'N'"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Insert new active version of template name
+func (o *HookTemplate) Insert() error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var last HookTemplate
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("name = ?", o.Name).Order("version desc").Limit(1).Find(&last)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if err := tx.Model(&HookTemplate{}).Where("name = ? and active = 'Y'", o.Name).Update("active", "N").Error; err != nil {
+			return err
+		}
+
+		o.ID = 0
+		o.Version = last.Version + 1
+		o.Active = "Y"
+		return tx.Create(o).Error
+	})
+	if err != nil {
+		logger.Error("HookTemplate.Insert() > ", err)
+	}
+	return err
+}
+
+// GetActive active version of template name
+func (o *HookTemplate) GetActive() (bool, error) {
+	result := db.Where("name = ? and active = 'Y'", o.Name).Limit(1).Find(o)
+	if result.Error != nil {
+		logger.Error("HookTemplate.GetActive() > ", result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Get template version by name and version
+func (o *HookTemplate) Get() error {
+	if o.Version == 0 {
+		return fmt.Errorf("version empty")
+	}
+	result := db.Where("name = ? and version = ?", o.Name, o.Version).Limit(1).Find(o)
+	if result.Error != nil {
+		logger.Error("HookTemplate.Get() > ", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("template '%s' version %d not found", o.Name, o.Version)
+	}
+	return nil
+}
+
+// GetList versions of template name, latest first
+func (o *HookTemplate) GetList(limit int) (r []HookTemplate, err error) {
+	if result := db.Where("name = ?", o.Name).Order("version desc").Limit(limit).Find(&r); result.Error != nil {
+		logger.Error("HookTemplate.GetList() > ", result.Error)
+		err = result.Error
+	}
+	return
+}
+
+// GetTemplateNames template names having active version
+func GetTemplateNames() (names []string, err error) {
+	if result := db.Model(&HookTemplate{}).Where("active = 'Y'").Distinct().Pluck("name", &names); result.Error != nil {
+		logger.Error("GetTemplateNames() > ", result.Error)
+		err = result.Error
+	}
+	return
+}